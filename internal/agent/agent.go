@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"service-monitor/internal/config"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+	"service-monitor/internal/probe"
+)
+
+// ServiceResult результат проверки одного сервиса агентом
+type ServiceResult struct {
+	ServiceID int          `json:"service_id" binding:"required"`
+	Result    probe.Result `json:"result"`
+}
+
+// ResultBatch результаты одного цикла проверок агента
+type ResultBatch struct {
+	Location string          `json:"location" binding:"required"`
+	Results  []ServiceResult `json:"results"`
+}
+
+// Agent получает список проверок с центрального сервера, выполняет их
+// теми же проверками, что и сервер, и отправляет результаты с меткой локации
+type Agent struct {
+	config *config.Config
+	logger *logger.Logger
+	client *http.Client
+}
+
+func New(cfg *config.Config, logger *logger.Logger) *Agent {
+	return &Agent{
+		config: cfg,
+		logger: logger,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run выполняет циклы проверок до отмены ctx
+func (a *Agent) Run(ctx context.Context) {
+	a.logger.Infof("Запуск агента в локации %s, сервер: %s", a.config.Location, a.config.ServerURL)
+
+	ticker := time.NewTicker(time.Duration(a.config.AgentPollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := a.runCycle(ctx); err != nil && ctx.Err() == nil {
+			a.logger.Error("Ошибка цикла проверок агента:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			a.logger.Info("Агент остановлен")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) runCycle(ctx context.Context) error {
+	var services []models.Service
+	if err := a.do(ctx, http.MethodGet, "/api/v1/agent/checks?location="+a.config.Location, nil, &services); err != nil {
+		return fmt.Errorf("получение проверок: %w", err)
+	}
+
+	batch := ResultBatch{Location: a.config.Location, Results: make([]ServiceResult, len(services))}
+
+	// Одновременно выполняется не больше CHECK_CONCURRENCY проверок
+	limit := make(chan struct{}, max(a.config.CheckConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		go func(i int, service models.Service) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			batch.Results[i] = ServiceResult{ServiceID: service.ID, Result: probe.Run(ctx, service)}
+		}(i, service)
+	}
+	wg.Wait()
+
+	// Прерванные проверки не отправляем
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err := a.do(ctx, http.MethodPost, "/api/v1/agent/results", batch, nil); err != nil {
+		return fmt.Errorf("отправка результатов: %w", err)
+	}
+	return nil
+}
+
+// do выполняет авторизованный запрос к центральному серверу
+func (a *Agent) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.config.ServerURL+path, &payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.config.AgentToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("сервер ответил HTTP %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package alerting
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+)
+
+// Manager единая точка открытия и разрешения алертов. Активный алерт
+// однозначно определяется отпечатком, поэтому повторное срабатывание
+// того же условия, в том числе на другом экземпляре, не создает дубль
+type Manager struct {
+	db     *database.DB
+	logger *logger.Logger
+}
+
+func NewManager(db *database.DB, logger *logger.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AlertColumns список колонок alerts в порядке, который ожидает ScanAlert
+const AlertColumns = `id, COALESCE(service_id, 0), slo_id, rule_id, message, severity, kind, fingerprint, labels,
+	is_resolved, created_at, resolved_at`
+
+// Кто оповещает об алерте, решается в одном месте, чтобы канал не получил
+// оповещение дважды: каналы правила, если у правила они заданы, иначе
+// политика эскалации сервиса, иначе дерево маршрутов. Условия написаны
+// для таблицы alerts под псевдонимом a
+const (
+	// ByRuleChannels алерт правила с непустым списком каналов
+	ByRuleChannels = `EXISTS (SELECT 1 FROM alert_rules r WHERE r.id = a.rule_id AND cardinality(r.channels) > 0)`
+	// ByEscalation алерт сервиса с политикой эскалации, не оповещаемый каналами правила
+	ByEscalation = `(NOT ` + ByRuleChannels + ` AND EXISTS (
+		SELECT 1 FROM services s WHERE s.id = a.service_id AND s.escalation_policy_id IS NOT NULL))`
+)
+
+// ScanAlert читает строку, выбранную через AlertColumns
+func ScanAlert(row database.Scanner, alert *models.Alert) error {
+	return row.Scan(&alert.ID, &alert.ServiceID, &alert.SLOID, &alert.RuleID, &alert.Message, &alert.Severity,
+		&alert.Kind, &alert.Fingerprint, &alert.Labels, &alert.IsResolved, &alert.CreatedAt, &alert.ResolvedAt)
+}
+
+// ServiceFingerprint отпечаток алерта о состоянии сервиса
+func ServiceFingerprint(serviceID int, kind string) string {
+	return fmt.Sprintf("service:%d:%s", serviceID, kind)
+}
+
+// Fire открывает алерт, если активного алерта с тем же отпечатком нет.
+// Возвращает ID созданного алерта или 0, если активный алерт уже есть
+func (m *Manager) Fire(alert models.Alert) (int, error) {
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = time.Now()
+	}
+	if alert.Labels == nil {
+		alert.Labels = models.Labels{}
+	}
+
+	query := `
+		INSERT INTO alerts (service_id, slo_id, rule_id, message, severity, kind, fingerprint, labels, is_resolved, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, false, $9)
+		ON CONFLICT (fingerprint) WHERE is_resolved = false DO NOTHING
+		RETURNING id
+	`
+
+	var serviceID sql.NullInt64
+	if alert.ServiceID != 0 {
+		serviceID = sql.NullInt64{Int64: int64(alert.ServiceID), Valid: true}
+	}
+
+	var id int
+	err := m.db.QueryRow(query, serviceID, alert.SLOID, alert.RuleID, alert.Message, alert.Severity, alert.Kind,
+		alert.Fingerprint, alert.Labels, alert.CreatedAt).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	m.logger.Infof("Создан алерт %d: %s", id, alert.Message)
+	return id, nil
+}
+
+// Resolve разрешает активные алерты с указанными отпечатками и возвращает их
+func (m *Manager) Resolve(fingerprints ...string) ([]models.Alert, error) {
+	query := `
+		UPDATE alerts
+		SET is_resolved = true, resolved_at = CURRENT_TIMESTAMP
+		WHERE fingerprint = ANY($1) AND is_resolved = false
+		RETURNING ` + AlertColumns
+
+	rows, err := m.db.Query(query, pq.Array(fingerprints))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resolved []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		if err := ScanAlert(rows, &alert); err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, alert)
+	}
+
+	return resolved, rows.Err()
+}
+
+// IsActive сообщает, есть ли активный алерт с отпечатком
+func (m *Manager) IsActive(fingerprint string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM alerts WHERE fingerprint = $1 AND is_resolved = false)`
+
+	var exists bool
+	err := m.db.QueryRow(query, fingerprint).Scan(&exists)
+	return exists, err
+}
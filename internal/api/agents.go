@@ -0,0 +1,142 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/agent"
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+)
+
+// agentAuth проверяет общий токен агентов; без AGENT_TOKEN API агентов недоступно
+func (s *Server) agentAuth(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	if s.config.AgentToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AgentToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен агента"})
+		return
+	}
+
+	c.Next()
+}
+
+func (s *Server) getAgentChecks(c *gin.Context) {
+	location := c.Query("location")
+	if location == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не указана локация"})
+		return
+	}
+
+	if err := s.touchAgent(location); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT ` + database.ServiceColumns + ` FROM services ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		var service models.Service
+		if err := database.ScanService(rows, &service); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Агент получает настройки с подставленными секретами; сервис
+		// с ненайденным секретом не проверяется из локации агента
+		resolved, _, err := s.secrets.ResolveService(service)
+		if err != nil {
+			s.logger.Errorf("Сервис %s не отправлен агенту %s: %v", service.Name, location, err)
+			continue
+		}
+		services = append(services, resolved)
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+func (s *Server) postAgentResults(c *gin.Context) {
+	var batch agent.ResultBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.touchAgent(batch.Location); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted := 0
+	for _, item := range batch.Results {
+		service, err := s.db.GetService(item.ServiceID)
+		if err != nil {
+			// Сервис могли удалить, пока агент выполнял проверку
+			continue
+		}
+
+		// Время фиксируем по часам сервера, чтобы кворум не зависел от часов агентов
+		result := item.Result
+		result.CheckedAt = time.Now()
+
+		// Агент проверял с подставленными секретами: прячем их значения
+		// в сообщениях так же, как у локальных проверок
+		if _, concealer, err := s.secrets.ResolveService(*service); err == nil {
+			result.RewriteMessages(concealer.Conceal)
+		}
+
+		if err := s.monitorService.Record(*service, batch.Location, &result); err != nil {
+			s.logger.Error("Ошибка сохранения результата агента:", err)
+			continue
+		}
+		accepted++
+	}
+
+	s.broadcastToClients(map[string]interface{}{
+		"type":      "service_update",
+		"timestamp": time.Now(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"accepted": accepted})
+}
+
+func (s *Server) getAgents(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT location, last_seen_at FROM agents ORDER BY location`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	agents := []models.Agent{}
+	for rows.Next() {
+		var a models.Agent
+		if err := rows.Scan(&a.Location, &a.LastSeenAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		agents = append(agents, a)
+	}
+
+	c.JSON(http.StatusOK, agents)
+}
+
+// touchAgent регистрирует агента и обновляет время последнего обращения
+func (s *Server) touchAgent(location string) error {
+	query := `
+		INSERT INTO agents (location, last_seen_at) VALUES ($1, CURRENT_TIMESTAMP)
+		ON CONFLICT (location) DO UPDATE SET last_seen_at = CURRENT_TIMESTAMP
+	`
+	_, err := s.db.Exec(query, location)
+	return err
+}
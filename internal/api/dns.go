@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/monitor"
+)
+
+// getDNSBaseline эталонные и последние ответы DNS проверки по локациям
+func (s *Server) getDNSBaseline(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	baselines, err := s.monitorService.DNSBaselines(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, baselines)
+}
+
+// acceptDNSBaseline принимает изменившиеся ответы DNS как новый эталон
+func (s *Server) acceptDNSBaseline(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	err = s.monitorService.AcceptDNSBaseline(id)
+	if errors.Is(err, monitor.ErrNoBaseline) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Эталонных ответов DNS нет"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Эталон DNS обновлен"})
+}
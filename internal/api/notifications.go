@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/notify"
+)
+
+// getAlertNotifications журнал оповещений по алерту: кому, через какой канал,
+// когда и с каким результатом выполнялась каждая попытка доставки
+func (s *Server) getAlertNotifications(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	query := `
+		SELECT o.id, o.alert_id, o.channel_id, ch.name, o.recipient, o.event, o.status, o.attempts,
+		       o.next_attempt_at, o.last_error, o.created_at, o.sent_at
+		FROM notification_outbox o
+		JOIN channels ch ON ch.id = o.channel_id
+		WHERE o.alert_id = $1
+		ORDER BY o.id
+	`
+
+	rows, err := s.db.Query(query, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	index := make(map[int]int)
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.AlertID, &n.ChannelID, &n.ChannelName, &n.Recipient, &n.Event, &n.Status,
+			&n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt, &n.SentAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if n.Status != notify.StatusPending {
+			n.NextAttemptAt = nil
+		}
+		n.Log = []models.DeliveryAttempt{}
+		index[n.ID] = len(notifications)
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	attemptsQuery := `
+		SELECT a.outbox_id, a.attempt, a.attempted_at, a.duration_ms, a.status_code, a.error, a.success
+		FROM notification_attempts a
+		JOIN notification_outbox o ON o.id = a.outbox_id
+		WHERE o.alert_id = $1
+		ORDER BY a.outbox_id, a.attempt
+	`
+
+	rows, err = s.db.Query(attemptsQuery, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var outboxID int
+		var a models.DeliveryAttempt
+		if err := rows.Scan(&outboxID, &a.Attempt, &a.AttemptedAt, &a.DurationMs, &a.StatusCode, &a.Error, &a.Success); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if i, ok := index[outboxID]; ok {
+			notifications[i].Log = append(notifications[i].Log, a)
+		}
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
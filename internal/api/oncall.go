@@ -0,0 +1,596 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/notify"
+	"service-monitor/internal/oncall"
+	"service-monitor/internal/secrets"
+)
+
+func (s *Server) getChannels(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT ` + notify.ChannelColumns + ` FROM channels ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	channels := []models.Channel{}
+	for rows.Next() {
+		var channel models.Channel
+		if err := notify.ScanChannel(rows, &channel); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		channels = append(channels, secrets.RedactChannel(channel))
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+func (s *Server) createChannel(c *gin.Context) {
+	var req models.ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := notify.ValidateTemplates(req.Config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.secrets.Check(req.Config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		INSERT INTO channels (name, type, config)
+		VALUES ($1, $2, $3)
+		RETURNING ` + notify.ChannelColumns
+
+	var channel models.Channel
+	if err := notify.ScanChannel(s.db.QueryRow(query, req.Name, req.Type, req.Config), &channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, secrets.RedactChannel(channel))
+}
+
+func (s *Server) updateChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var req models.ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := notify.ValidateTemplates(req.Config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stored models.Channel
+	err = notify.ScanChannel(s.db.QueryRow(`SELECT `+notify.ChannelColumns+` FROM channels WHERE id = $1`, id), &stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Канал не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Скрытые в ответах API значения клиент присылает обратно маской
+	secrets.RestoreChannel(&req.Config, stored.Config)
+	if err := s.secrets.Check(req.Config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		UPDATE channels SET name = $2, type = $3, config = $4
+		WHERE id = $1
+		RETURNING ` + notify.ChannelColumns
+
+	var channel models.Channel
+	if err := notify.ScanChannel(s.db.QueryRow(query, id, req.Name, req.Type, req.Config), &channel); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Канал не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, secrets.RedactChannel(channel))
+}
+
+func (s *Server) deleteChannel(c *gin.Context) {
+	s.deleteByID(c, "channels", "Канал не найден", "Канал удален")
+}
+
+func (s *Server) getResponders(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT id, name, channel_id, created_at FROM responders ORDER BY name`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	responders := []models.Responder{}
+	for rows.Next() {
+		var r models.Responder
+		if err := rows.Scan(&r.ID, &r.Name, &r.ChannelID, &r.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		responders = append(responders, r)
+	}
+
+	c.JSON(http.StatusOK, responders)
+}
+
+func (s *Server) createResponder(c *gin.Context) {
+	var req models.ResponderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		INSERT INTO responders (name, channel_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	r := models.Responder{Name: req.Name, ChannelID: req.ChannelID}
+	if err := s.db.QueryRow(query, req.Name, req.ChannelID).Scan(&r.ID, &r.CreatedAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, r)
+}
+
+func (s *Server) updateResponder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var req models.ResponderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		UPDATE responders SET name = $2, channel_id = $3
+		WHERE id = $1
+		RETURNING id, name, channel_id, created_at
+	`
+
+	var r models.Responder
+	if err := s.db.QueryRow(query, id, req.Name, req.ChannelID).Scan(&r.ID, &r.Name, &r.ChannelID, &r.CreatedAt); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дежурный не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+func (s *Server) deleteResponder(c *gin.Context) {
+	s.deleteByID(c, "responders", "Дежурный не найден", "Дежурный удален")
+}
+
+func (s *Server) getSchedules(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT ` + oncall.ScheduleColumns + ` FROM schedules ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	schedules := []models.Schedule{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := oncall.ScanSchedule(rows, &schedule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// getSchedule возвращает график вместе с текущими и будущими подменами
+func (s *Server) getSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var schedule models.Schedule
+	err = oncall.ScanSchedule(s.db.QueryRow(`SELECT `+oncall.ScheduleColumns+` FROM schedules WHERE id = $1`, id), &schedule)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "График не найден"})
+		return
+	}
+
+	now := time.Now()
+	schedule.Overrides, err = s.oncall.Overrides(id, now, now.AddDate(1, 0, 0))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (s *Server) createSchedule(c *gin.Context) {
+	var req models.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		INSERT INTO schedules (name, rotation, starts_at, participants)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + oncall.ScheduleColumns
+
+	var schedule models.Schedule
+	err := oncall.ScanSchedule(s.db.QueryRow(query, req.Name, req.Rotation, req.StartsAt, pq.Array(req.Participants)), &schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (s *Server) updateSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var req models.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		UPDATE schedules SET name = $2, rotation = $3, starts_at = $4, participants = $5
+		WHERE id = $1
+		RETURNING ` + oncall.ScheduleColumns
+
+	var schedule models.Schedule
+	err = oncall.ScanSchedule(s.db.QueryRow(query, id, req.Name, req.Rotation, req.StartsAt, pq.Array(req.Participants)), &schedule)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "График не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (s *Server) deleteSchedule(c *gin.Context) {
+	s.deleteByID(c, "schedules", "График не найден", "График удален")
+}
+
+func (s *Server) createOverride(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var req models.OverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at должен быть позже starts_at"})
+		return
+	}
+
+	query := `
+		INSERT INTO schedule_overrides (schedule_id, responder_id, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	o := models.ScheduleOverride{ScheduleID: id, ResponderID: req.ResponderID, StartsAt: req.StartsAt, EndsAt: req.EndsAt}
+	if err := s.db.QueryRow(query, id, req.ResponderID, req.StartsAt, req.EndsAt).Scan(&o.ID, &o.CreatedAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, o)
+}
+
+func (s *Server) deleteOverride(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+	overrideID, err := strconv.Atoi(c.Param("override_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подмены"})
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM schedule_overrides WHERE id = $1 AND schedule_id = $2`, overrideID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Подмена не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Подмена удалена"})
+}
+
+// getOnCall кто дежурит сейчас (или в момент ?at= в RFC3339) по всем графикам
+func (s *Server) getOnCall(c *gin.Context) {
+	at, ok := onCallTime(c)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.Query(`SELECT id FROM schedules ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	result := []models.OnCall{}
+	for _, id := range ids {
+		onCall, err := s.oncall.OnCall(id, at)
+		if errors.Is(err, oncall.ErrNoSchedule) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result = append(result, *onCall)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getScheduleOnCall кто дежурит по графику сейчас (или в момент ?at=)
+func (s *Server) getScheduleOnCall(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	at, ok := onCallTime(c)
+	if !ok {
+		return
+	}
+
+	onCall, err := s.oncall.OnCall(id, at)
+	if errors.Is(err, oncall.ErrNoSchedule) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "График не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, onCall)
+}
+
+func onCallTime(c *gin.Context) (time.Time, bool) {
+	value := c.Query("at")
+	if value == "" {
+		return time.Now(), true
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный at: ожидается RFC3339"})
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+func (s *Server) getEscalationPolicies(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT id, name, levels, created_at FROM escalation_policies ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	policies := []models.EscalationPolicy{}
+	for rows.Next() {
+		var p models.EscalationPolicy
+		if err := rows.Scan(&p.ID, &p.Name, &p.Levels, &p.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		policies = append(policies, p)
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (s *Server) createEscalationPolicy(c *gin.Context) {
+	req, ok := bindEscalationPolicy(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		INSERT INTO escalation_policies (name, levels)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	p := models.EscalationPolicy{Name: req.Name, Levels: req.Levels}
+	if err := s.db.QueryRow(query, req.Name, req.Levels).Scan(&p.ID, &p.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+func (s *Server) updateEscalationPolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	req, ok := bindEscalationPolicy(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		UPDATE escalation_policies SET name = $2, levels = $3
+		WHERE id = $1
+		RETURNING id, name, levels, created_at
+	`
+
+	var p models.EscalationPolicy
+	if err := s.db.QueryRow(query, id, req.Name, req.Levels).Scan(&p.ID, &p.Name, &p.Levels, &p.CreatedAt); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Политика не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+func (s *Server) deleteEscalationPolicy(c *gin.Context) {
+	s.deleteByID(c, "escalation_policies", "Политика не найдена", "Политика удалена")
+}
+
+// bindEscalationPolicy читает политику и проверяет, что у каждого уровня одна цель
+func bindEscalationPolicy(c *gin.Context) (models.EscalationPolicyRequest, bool) {
+	var req models.EscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+
+	for i, level := range req.Levels {
+		targets := 0
+		for _, target := range []*int{level.ScheduleID, level.ResponderID, level.ChannelID} {
+			if target != nil {
+				targets++
+			}
+		}
+		if targets != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf(
+				"Уровень %d: укажите ровно одно из schedule_id, responder_id, channel_id", i+1)})
+			return req, false
+		}
+		if level.TimeoutMinutes < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Уровень %d: timeout_minutes не может быть отрицательным", i+1)})
+			return req, false
+		}
+	}
+
+	return req, true
+}
+
+// acknowledgeAlert подтверждает алерт и останавливает его эскалацию
+func (s *Server) acknowledgeAlert(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var req models.AcknowledgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		UPDATE alerts SET acknowledged_at = CURRENT_TIMESTAMP, acknowledged_by = $2
+		WHERE id = $1 AND is_resolved = false AND acknowledged_at IS NULL
+		RETURNING acknowledged_at
+	`
+
+	var acknowledgedAt time.Time
+	err = s.db.QueryRow(query, id, req.By).Scan(&acknowledgedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Алерт не найден, уже подтвержден или разрешен"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Алерт подтвержден", "acknowledged_at": acknowledgedAt})
+}
+
+// deleteByID удаляет запись таблицы по ID из пути. Ошибка удаления - обычно
+// ссылка из другой таблицы, поэтому возвращается 409
+func (s *Server) deleteByID(c *gin.Context, table, notFound, deleted string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM `+table+` WHERE id = $1`, id)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": deleted})
+}
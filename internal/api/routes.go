@@ -0,0 +1,96 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+	"service-monitor/internal/routing"
+)
+
+func (s *Server) getRoutes(c *gin.Context) {
+	root, err := routing.Load(s.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, root)
+}
+
+// updateRoutes заменяет дерево маршрутизации целиком
+func (s *Server) updateRoutes(c *gin.Context) {
+	var root models.Route
+	if err := c.ShouldBindJSON(&root); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := routing.Validate(root); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := routing.Save(s.db, root); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, root)
+}
+
+// testRoutes показывает, какие маршруты и каналы получил бы алерт: существующий
+// (по alert_id, с метками его сервиса) или с произвольными метками
+func (s *Server) testRoutes(c *gin.Context) {
+	var req models.RouteTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	root, err := routing.Load(s.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	labels := req.Labels
+	if req.AlertID != nil {
+		var alert models.Alert
+		err := alerting.ScanAlert(s.db.QueryRow(`SELECT `+alerting.AlertColumns+` FROM alerts WHERE id = $1`, *req.AlertID), &alert)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Алерт не найден"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var service *models.Service
+		if alert.ServiceID != 0 {
+			service = &models.Service{}
+			err := database.ScanService(s.db.QueryRow(`SELECT `+database.ServiceColumns+` FROM services WHERE id = $1`, alert.ServiceID), service)
+			if errors.Is(err, sql.ErrNoRows) {
+				service = nil
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		labels = routing.AlertLabels(alert, service)
+	}
+	if labels == nil {
+		labels = models.Labels{}
+	}
+
+	matched := routing.Match(root, labels)
+	c.JSON(http.StatusOK, models.RouteTestResult{
+		Labels:   labels,
+		Routes:   matched,
+		Channels: routing.Channels(matched),
+	})
+}
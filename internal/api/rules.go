@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/rules"
+)
+
+func (s *Server) getAlertRules(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT ` + rules.RuleColumns + ` FROM alert_rules ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	items := []models.AlertRule{}
+	for rows.Next() {
+		var rule models.AlertRule
+		if err := rules.ScanRule(rows, &rule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		items = append(items, rule)
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (s *Server) getAlertRule(c *gin.Context) {
+	rule, ok := s.loadAlertRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) createAlertRule(c *gin.Context) {
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		INSERT INTO alert_rules (name, service_id, group_name, metric, operator, threshold, window_seconds, for_seconds,
+		                         severity, labels, channels, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + rules.RuleColumns
+
+	var created models.AlertRule
+	err := rules.ScanRule(s.db.QueryRow(query, rule.Name, rule.ServiceID, rule.Group, rule.Metric, rule.Operator,
+		rule.Threshold, rule.WindowSeconds, rule.ForSeconds, rule.Severity, rule.Labels, pq.Array(rule.Channels),
+		rule.Enabled), &created)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (s *Server) updateAlertRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		UPDATE alert_rules
+		SET name = $2, service_id = $3, group_name = $4, metric = $5, operator = $6, threshold = $7,
+		    window_seconds = $8, for_seconds = $9, severity = $10, labels = $11, channels = $12, enabled = $13
+		WHERE id = $1
+		RETURNING ` + rules.RuleColumns
+
+	var updated models.AlertRule
+	err = rules.ScanRule(s.db.QueryRow(query, id, rule.Name, rule.ServiceID, rule.Group, rule.Metric, rule.Operator,
+		rule.Threshold, rule.WindowSeconds, rule.ForSeconds, rule.Severity, rule.Labels, pq.Array(rule.Channels),
+		rule.Enabled), &updated)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Правило не найдено"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (s *Server) deleteAlertRule(c *gin.Context) {
+	s.deleteByID(c, "alert_rules", "Правило не найдено", "Правило удалено")
+}
+
+// previewAlertRule вычисляет несохраненное правило на исторических данных
+func (s *Server) previewAlertRule(c *gin.Context) {
+	rule, ok := bindAlertRule(c)
+	if !ok {
+		return
+	}
+	s.preview(c, rule)
+}
+
+// previewSavedAlertRule вычисляет сохраненное правило на исторических данных
+func (s *Server) previewSavedAlertRule(c *gin.Context) {
+	rule, ok := s.loadAlertRule(c)
+	if !ok {
+		return
+	}
+	s.preview(c, rule)
+}
+
+// preview считает правило за ?period= (по умолчанию 24h) с шагом ?step= (5m)
+func (s *Server) preview(c *gin.Context, rule models.AlertRule) {
+	period, err := parseWindow(c.DefaultQuery("period", "24h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный period: " + err.Error()})
+		return
+	}
+
+	step, err := parseWindow(c.DefaultQuery("step", "5m"))
+	if err != nil || step < time.Minute {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный step: минимум 1m"})
+		return
+	}
+
+	if period/step > rules.MaxPreviewPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Слишком много точек, максимум %d", rules.MaxPreviewPoints)})
+		return
+	}
+
+	to := time.Now().Truncate(step)
+	previews, err := s.ruleEvaluator.Preview(rule, to.Add(-period), to, step)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, previews)
+}
+
+func (s *Server) loadAlertRule(c *gin.Context) (models.AlertRule, bool) {
+	var rule models.AlertRule
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return rule, false
+	}
+
+	if err := rules.ScanRule(s.db.QueryRow(`SELECT `+rules.RuleColumns+` FROM alert_rules WHERE id = $1`, id), &rule); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Правило не найдено"})
+		return rule, false
+	}
+	return rule, true
+}
+
+// bindAlertRule читает правило из запроса, подставляет значения по умолчанию
+// и проверяет его
+func bindAlertRule(c *gin.Context) (models.AlertRule, bool) {
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.AlertRule{}, false
+	}
+
+	rule := models.AlertRule{
+		Name:          req.Name,
+		ServiceID:     req.ServiceID,
+		Group:         req.Group,
+		Metric:        req.Metric,
+		Operator:      req.Operator,
+		Threshold:     req.Threshold,
+		WindowSeconds: req.WindowSeconds,
+		ForSeconds:    req.ForSeconds,
+		Severity:      req.Severity,
+		Labels:        req.Labels,
+		Channels:      req.Channels,
+		Enabled:       true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if rule.WindowSeconds == 0 {
+		rule.WindowSeconds = 300
+	}
+	if rule.Labels == nil {
+		rule.Labels = models.Labels{}
+	}
+	if rule.Channels == nil {
+		rule.Channels = []int{}
+	}
+
+	if err := rules.Validate(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return rule, false
+	}
+	return rule, true
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/probe"
+	"service-monitor/internal/secrets"
+)
+
+func (s *Server) getSecrets(c *gin.Context) {
+	list, err := s.secrets.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// putSecret создает или заменяет секрет; значение в ответе не возвращается
+func (s *Server) putSecret(c *gin.Context) {
+	name := c.Param("name")
+	if err := secrets.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req models.SecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := s.secrets.Set(name, req.Value)
+	if errors.Is(err, secrets.ErrDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, secret)
+}
+
+// deleteSecret удаляет секрет, если на него никто не ссылается
+func (s *Server) deleteSecret(c *gin.Context) {
+	name := c.Param("name")
+
+	usages, err := s.secrets.Usages(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(usages) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Секрет используется: " + strings.Join(usages, ", ")})
+		return
+	}
+
+	err = s.secrets.Delete(name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Секрет не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Секрет удален"})
+}
+
+// validateProbe проверяет настройки проверки с подставленными секретами:
+// ссылки на несуществующие секреты - ошибка запроса
+func (s *Server) validateProbe(url string, cfg models.ProbeConfig) error {
+	service, concealer, err := s.secrets.ResolveService(models.Service{URL: url, ProbeConfig: cfg})
+	if err != nil {
+		return err
+	}
+	return concealer.ConcealError(probe.Validate(service.ProbeConfig))
+}
@@ -1,46 +1,76 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"service-monitor/internal/cluster"
 	"service-monitor/internal/config"
 	"service-monitor/internal/database"
 	"service-monitor/internal/logger"
 	"service-monitor/internal/monitor"
 	"service-monitor/internal/models"
+	"service-monitor/internal/oncall"
+	"service-monitor/internal/probe"
+	"service-monitor/internal/rules"
+	"service-monitor/internal/secrets"
+	"service-monitor/internal/slo"
+	"service-monitor/internal/uptime"
 )
 
 type Server struct {
 	config         *config.Config
 	db             *database.DB
 	monitorService *monitor.Service
+	elector        *cluster.Elector
+	sloEvaluator   *slo.Evaluator
+	ruleEvaluator  *rules.Evaluator
+	secrets        *secrets.Store
+	uptime         *uptime.Calculator
+	oncall         *oncall.Resolver
 	logger         *logger.Logger
 	upgrader       websocket.Upgrader
-	clients        map[*websocket.Conn]bool
+	httpServer     *http.Server
+
+	clientsMu sync.Mutex
+	clients   map[*websocket.Conn]bool
 }
 
-func NewServer(cfg *config.Config, db *database.DB, monitorService *monitor.Service, logger *logger.Logger) *Server {
+func NewServer(cfg *config.Config, db *database.DB, monitorService *monitor.Service, elector *cluster.Elector,
+	sloEvaluator *slo.Evaluator, ruleEvaluator *rules.Evaluator, secrets *secrets.Store, logger *logger.Logger) *Server {
 	return &Server{
 		config:         cfg,
 		db:             db,
 		monitorService: monitorService,
+		elector:        elector,
+		sloEvaluator:   sloEvaluator,
+		ruleEvaluator:  ruleEvaluator,
+		secrets:        secrets,
+		uptime:         uptime.NewCalculator(db),
+		oncall:         oncall.NewResolver(db),
 		logger:         logger,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true 
 			},
 		},
+		httpServer: &http.Server{
+			Addr: ":" + cfg.Port,
+		},
 		clients: make(map[*websocket.Conn]bool),
 	}
 }
 
+// Run запускает HTTP сервер и блокируется до вызова Shutdown
 func (s *Server) Run() error {
 	router := gin.Default()
 
@@ -74,22 +104,111 @@ func (s *Server) Run() error {
 		api.GET("/services/:id", s.getService)
 		api.PUT("/services/:id", s.updateService)
 		api.DELETE("/services/:id", s.deleteService)
+		api.POST("/services/:id/check", s.checkServiceNow)
+		api.GET("/services/:id/dns-baseline", s.getDNSBaseline)
+		api.POST("/services/:id/dns-baseline/accept", s.acceptDNSBaseline)
+
+		// Пробная проверка без сохранения
+		api.POST("/probe/test", s.testProbe)
 		
 		// Проверки здоровья
 		api.GET("/services/:id/checks", s.getServiceChecks)
+		api.GET("/services/:id/timeseries", s.getServiceTimeSeries)
+		api.GET("/services/:id/uptime", s.getServiceUptime)
+
+		// Окна обслуживания
+		api.GET("/maintenance", s.getMaintenanceWindows)
+		api.POST("/maintenance", s.createMaintenanceWindow)
+		api.DELETE("/maintenance/:id", s.deleteMaintenanceWindow)
 		
 		// Алерты
 		api.GET("/alerts", s.getAlerts)
 		api.PUT("/alerts/:id/resolve", s.resolveAlert)
+		api.PUT("/alerts/:id/acknowledge", s.acknowledgeAlert)
+		api.GET("/alerts/:id/notifications", s.getAlertNotifications)
+
+		// Каналы оповещений, дежурства и эскалация
+		api.GET("/channels", s.getChannels)
+		api.POST("/channels", s.createChannel)
+		api.PUT("/channels/:id", s.updateChannel)
+		api.DELETE("/channels/:id", s.deleteChannel)
+		api.GET("/responders", s.getResponders)
+		api.POST("/responders", s.createResponder)
+		api.PUT("/responders/:id", s.updateResponder)
+		api.DELETE("/responders/:id", s.deleteResponder)
+		api.GET("/schedules", s.getSchedules)
+		api.POST("/schedules", s.createSchedule)
+		api.GET("/schedules/:id", s.getSchedule)
+		api.PUT("/schedules/:id", s.updateSchedule)
+		api.DELETE("/schedules/:id", s.deleteSchedule)
+		api.GET("/schedules/:id/oncall", s.getScheduleOnCall)
+		api.POST("/schedules/:id/overrides", s.createOverride)
+		api.DELETE("/schedules/:id/overrides/:override_id", s.deleteOverride)
+		api.GET("/oncall", s.getOnCall)
+		api.GET("/escalation-policies", s.getEscalationPolicies)
+		api.POST("/escalation-policies", s.createEscalationPolicy)
+		api.PUT("/escalation-policies/:id", s.updateEscalationPolicy)
+		api.DELETE("/escalation-policies/:id", s.deleteEscalationPolicy)
 		
+		// Цели надежности
+		api.GET("/slos", s.getSLOs)
+		api.POST("/slos", s.createSLO)
+		api.GET("/slos/:id", s.getSLO)
+		api.PUT("/slos/:id", s.updateSLO)
+		api.DELETE("/slos/:id", s.deleteSLO)
+
+		// Пользовательские правила алертов
+		api.GET("/alert-rules", s.getAlertRules)
+		api.POST("/alert-rules", s.createAlertRule)
+		api.POST("/alert-rules/preview", s.previewAlertRule)
+		api.GET("/alert-rules/:id", s.getAlertRule)
+		api.PUT("/alert-rules/:id", s.updateAlertRule)
+		api.DELETE("/alert-rules/:id", s.deleteAlertRule)
+		api.GET("/alert-rules/:id/preview", s.previewSavedAlertRule)
+
+		// Маршрутизация оповещений по меткам
+		api.GET("/routes", s.getRoutes)
+		api.PUT("/routes", s.updateRoutes)
+		api.POST("/routes/test", s.testRoutes)
+
+		// Хранилище секретов
+		api.GET("/secrets", s.getSecrets)
+		api.PUT("/secrets/:name", s.putSecret)
+		api.DELETE("/secrets/:name", s.deleteSecret)
+
 		// Статистика
 		api.GET("/stats", s.getStats)
+
+		// Состояние экземпляра в кластере
+		api.GET("/cluster", s.getClusterStatus)
+
+		// Пул проверок и запись результатов: очередь, отставание, буфер
+		api.GET("/monitor/pool", s.getPoolStats)
+		api.GET("/monitor/writer", s.getWriterStats)
+
+		// Удаленные агенты проверок
+		api.GET("/agents", s.getAgents)
+		agentAPI := api.Group("/agent", s.agentAuth)
+		agentAPI.GET("/checks", s.getAgentChecks)
+		agentAPI.POST("/results", s.postAgentResults)
 		
 		// WebSocket для real-time обновлений
 		api.GET("/ws", s.handleWebSocket)
 	}
 
-	return router.Run(":" + s.config.Port)
+	s.httpServer.Handler = router
+
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown закрывает WebSocket соединения с close frame и дожидается
+// завершения активных HTTP запросов до истечения ctx
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closeClients()
+	return s.httpServer.Shutdown(ctx)
 }
 
 func (s *Server) handleDashboard(c *gin.Context) {
@@ -100,19 +219,12 @@ func (s *Server) handleDashboard(c *gin.Context) {
 
 func (s *Server) getServices(c *gin.Context) {
 	query := `
-		SELECT s.*, 
-		       hc.status as last_status,
-		       hc.checked_at as last_check,
-		       COALESCE(
-		           (SELECT COUNT(*) * 100.0 / NULLIF(COUNT(*) OVER(), 0)
-		            FROM health_checks hc2 
-		            WHERE hc2.service_id = s.id 
-		            AND hc2.status = 'healthy'
-		            AND hc2.checked_at >= NOW() - INTERVAL '24 hours'), 0
-		       ) as uptime
+		SELECT ` + database.ServiceColumns + `,
+		       s.status as last_status,
+		       hc.checked_at as last_check
 		FROM services s
 		LEFT JOIN LATERAL (
-			SELECT status, checked_at 
+			SELECT checked_at 
 			FROM health_checks 
 			WHERE service_id = s.id 
 			ORDER BY checked_at DESC 
@@ -132,20 +244,8 @@ func (s *Server) getServices(c *gin.Context) {
 	for rows.Next() {
 		var service models.Service
 		var lastStatus, lastCheck sql.NullString
-		var uptime sql.NullFloat64
 		
-		err := rows.Scan(
-			&service.ID,
-			&service.Name,
-			&service.URL,
-			&service.CheckInterval,
-			&service.Timeout,
-			&service.CreatedAt,
-			&service.UpdatedAt,
-			&lastStatus,
-			&lastCheck,
-			&uptime,
-		)
+		err := rows.Scan(append(database.ServiceFields(&service), &lastStatus, &lastCheck)...)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -159,12 +259,21 @@ func (s *Server) getServices(c *gin.Context) {
 				service.LastCheck = t
 			}
 		}
-		if uptime.Valid {
-			service.Uptime = uptime.Float64
-		}
 
 		services = append(services, service)
 	}
+	rows.Close()
+
+	// Uptime за 24 часа взвешивается по времени в каждом статусе
+	uptimes, err := s.recentUptime(services)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range services {
+		services[i].Uptime = uptimes[services[i].ID].Uptime
+		services[i] = secrets.RedactService(services[i])
+	}
 
 	c.JSON(http.StatusOK, services)
 }
@@ -175,6 +284,10 @@ func (s *Server) createService(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := s.validateProbe(req.URL, req.ProbeConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Устанавливаем значения по умолчанию
 	if req.CheckInterval == 0 {
@@ -183,15 +296,20 @@ func (s *Server) createService(c *gin.Context) {
 	if req.Timeout == 0 {
 		req.Timeout = 10
 	}
+	if req.Labels == nil {
+		req.Labels = models.Labels{}
+	}
 
 	query := `
-		INSERT INTO services (name, url, check_interval, timeout)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO services (name, url, group_name, check_interval, timeout, probe_config, degraded_threshold, degraded_p95_threshold,
+		                      escalation_policy_id, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 	
 	var service models.Service
-	err := s.db.QueryRow(query, req.Name, req.URL, req.CheckInterval, req.Timeout).
+	err := s.db.QueryRow(query, req.Name, req.URL, req.Group, req.CheckInterval, req.Timeout, req.ProbeConfig,
+		req.DegradedThreshold, req.DegradedP95Threshold, req.EscalationPolicyID, req.Labels).
 		Scan(&service.ID, &service.CreatedAt, &service.UpdatedAt)
 	
 	if err != nil {
@@ -201,10 +319,16 @@ func (s *Server) createService(c *gin.Context) {
 
 	service.Name = req.Name
 	service.URL = req.URL
+	service.Group = req.Group
 	service.CheckInterval = req.CheckInterval
 	service.Timeout = req.Timeout
+	service.ProbeConfig = req.ProbeConfig
+	service.DegradedThreshold = req.DegradedThreshold
+	service.DegradedP95Threshold = req.DegradedP95Threshold
+	service.EscalationPolicyID = req.EscalationPolicyID
+	service.Labels = req.Labels
 
-	c.JSON(http.StatusCreated, service)
+	c.JSON(http.StatusCreated, secrets.RedactService(service))
 }
 
 func (s *Server) getService(c *gin.Context) {
@@ -214,25 +338,13 @@ func (s *Server) getService(c *gin.Context) {
 		return
 	}
 
-	query := `SELECT id, name, url, check_interval, timeout, created_at, updated_at FROM services WHERE id = $1`
-	
-	var service models.Service
-	err = s.db.QueryRow(query, id).Scan(
-		&service.ID,
-		&service.Name,
-		&service.URL,
-		&service.CheckInterval,
-		&service.Timeout,
-		&service.CreatedAt,
-		&service.UpdatedAt,
-	)
-	
+	service, err := s.db.GetService(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Сервис не найден"})
 		return
 	}
 
-	c.JSON(http.StatusOK, service)
+	c.JSON(http.StatusOK, secrets.RedactService(*service))
 }
 
 func (s *Server) updateService(c *gin.Context) {
@@ -247,6 +359,28 @@ func (s *Server) updateService(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.URL != "" || req.ProbeConfig != nil {
+		stored, err := s.db.GetService(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Сервис не найден"})
+			return
+		}
+
+		// Скрытые в ответах API значения клиент присылает обратно маской
+		url, cfg := stored.URL, stored.ProbeConfig
+		if req.URL != "" {
+			secrets.RestoreURL(&req.URL, stored.URL)
+			url = req.URL
+		}
+		if req.ProbeConfig != nil {
+			secrets.RestoreProbe(req.ProbeConfig, stored.ProbeConfig)
+			cfg = *req.ProbeConfig
+		}
+		if err := s.validateProbe(url, cfg); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	query := `
 		UPDATE services 
@@ -254,28 +388,27 @@ func (s *Server) updateService(c *gin.Context) {
 		    url = COALESCE($3, url),
 		    check_interval = COALESCE($4, check_interval),
 		    timeout = COALESCE($5, timeout),
+		    probe_config = COALESCE($6, probe_config),
+		    degraded_threshold = COALESCE($7, degraded_threshold),
+		    degraded_p95_threshold = COALESCE($8, degraded_p95_threshold),
+		    group_name = COALESCE($9, group_name),
+		    escalation_policy_id = CASE WHEN $10::int IS NULL THEN escalation_policy_id ELSE NULLIF($10, 0) END,
+		    labels = COALESCE($11, labels),
 		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = $1
-		RETURNING id, name, url, check_interval, timeout, created_at, updated_at
+		RETURNING ` + database.ServiceColumns + `
 	`
 	
 	var service models.Service
-	err = s.db.QueryRow(query, id, req.Name, req.URL, req.CheckInterval, req.Timeout).Scan(
-		&service.ID,
-		&service.Name,
-		&service.URL,
-		&service.CheckInterval,
-		&service.Timeout,
-		&service.CreatedAt,
-		&service.UpdatedAt,
-	)
+	err = database.ScanService(s.db.QueryRow(query, id, req.Name, req.URL, req.CheckInterval, req.Timeout, req.ProbeConfig,
+		req.DegradedThreshold, req.DegradedP95Threshold, req.Group, req.EscalationPolicyID, req.Labels), &service)
 	
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Сервис не найден"})
 		return
 	}
 
-	c.JSON(http.StatusOK, service)
+	c.JSON(http.StatusOK, secrets.RedactService(service))
 }
 
 func (s *Server) deleteService(c *gin.Context) {
@@ -301,6 +434,61 @@ func (s *Server) deleteService(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Сервис удален"})
 }
 
+func (s *Server) checkServiceNow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	service, err := s.db.GetService(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сервис не найден"})
+		return
+	}
+
+	result, err := s.monitorService.CheckNow(c.Request.Context(), *service)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.broadcastToClients(map[string]interface{}{
+		"type":       "service_update",
+		"service_id": service.ID,
+		"timestamp":  time.Now(),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) testProbe(c *gin.Context) {
+	var req models.ProbeTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.validateProbe(req.URL, req.ProbeConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	service, concealer, err := s.secrets.ResolveService(models.Service{
+		Name:        "probe-test",
+		URL:         req.URL,
+		Timeout:     req.Timeout,
+		ProbeConfig: req.ProbeConfig,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := probe.Run(c.Request.Context(), service)
+	result.RewriteMessages(concealer.Conceal)
+	c.JSON(http.StatusOK, result)
+}
+
 func (s *Server) getServiceChecks(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -316,7 +504,8 @@ func (s *Server) getServiceChecks(c *gin.Context) {
 	}
 
 	query := `
-		SELECT id, service_id, status, response_time, error_message, checked_at
+		SELECT id, service_id, status, response_time, error_message, checked_at, location,
+		       dns_time, connect_time, tls_time, ttfb, transfer_time, packet_loss
 		FROM health_checks
 		WHERE service_id = $1
 		ORDER BY checked_at DESC
@@ -333,6 +522,7 @@ func (s *Server) getServiceChecks(c *gin.Context) {
 	var checks []models.HealthCheck
 	for rows.Next() {
 		var check models.HealthCheck
+		var dns, connect, tlsTime, ttfb, transfer sql.NullFloat64
 		err := rows.Scan(
 			&check.ID,
 			&check.ServiceID,
@@ -340,11 +530,30 @@ func (s *Server) getServiceChecks(c *gin.Context) {
 			&check.ResponseTime,
 			&check.ErrorMessage,
 			&check.CheckedAt,
+			&check.Location,
+			&dns,
+			&connect,
+			&tlsTime,
+			&ttfb,
+			&transfer,
+			&check.PacketLoss,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+
+		// Старые проверки и не-HTTP проверки не имеют разбивки по фазам
+		if dns.Valid {
+			check.Timings = &models.Timings{
+				DNS:      dns.Float64,
+				Connect:  connect.Float64,
+				TLS:      tlsTime.Float64,
+				TTFB:     ttfb.Float64,
+				Transfer: transfer.Float64,
+			}
+		}
+
 		checks = append(checks, check)
 	}
 
@@ -353,7 +562,9 @@ func (s *Server) getServiceChecks(c *gin.Context) {
 
 func (s *Server) getAlerts(c *gin.Context) {
 	query := `
-		SELECT a.id, a.service_id, a.message, a.severity, a.is_resolved, a.created_at, a.resolved_at,
+		SELECT a.id, COALESCE(a.service_id, 0), a.slo_id, a.rule_id, a.message, a.severity, a.kind, a.fingerprint, a.labels,
+		       a.is_resolved, a.created_at, a.resolved_at,
+		       a.acknowledged_at, a.acknowledged_by, a.escalation_level,
 		       s.name as service_name
 		FROM alerts a
 		LEFT JOIN services s ON a.service_id = s.id
@@ -377,11 +588,19 @@ func (s *Server) getAlerts(c *gin.Context) {
 		err := rows.Scan(
 			&alert.ID,
 			&alert.ServiceID,
+			&alert.SLOID,
+			&alert.RuleID,
 			&alert.Message,
 			&alert.Severity,
+			&alert.Kind,
+			&alert.Fingerprint,
+			&alert.Labels,
 			&alert.IsResolved,
 			&alert.CreatedAt,
 			&resolvedAt,
+			&alert.AcknowledgedAt,
+			&alert.AcknowledgedBy,
+			&alert.EscalationLevel,
 			&serviceName,
 		)
 		if err != nil {
@@ -437,18 +656,11 @@ func (s *Server) getStats(c *gin.Context) {
 	statsQuery := `
 		SELECT 
 			COUNT(*) as total_services,
-			COUNT(CASE WHEN hc.status = 'healthy' THEN 1 END) as healthy_services,
-			COUNT(CASE WHEN hc.status = 'unhealthy' THEN 1 END) as unhealthy_services,
-			COUNT(CASE WHEN a.is_resolved = false THEN 1 END) as active_alerts
+			COUNT(CASE WHEN s.status = 'healthy' THEN 1 END) as healthy_services,
+			COUNT(CASE WHEN s.status = 'unhealthy' THEN 1 END) as unhealthy_services,
+			COUNT(CASE WHEN s.status = 'degraded' THEN 1 END) as degraded_services,
+			(SELECT COUNT(*) FROM alerts WHERE is_resolved = false) as active_alerts
 		FROM services s
-		LEFT JOIN LATERAL (
-			SELECT status 
-			FROM health_checks 
-			WHERE service_id = s.id 
-			ORDER BY checked_at DESC 
-			LIMIT 1
-		) hc ON true
-		LEFT JOIN alerts a ON a.service_id = s.id AND a.is_resolved = false
 	`
 	
 	var stats models.DashboardStats
@@ -456,6 +668,7 @@ func (s *Server) getStats(c *gin.Context) {
 		&stats.TotalServices,
 		&stats.HealthyServices,
 		&stats.UnhealthyServices,
+		&stats.DegradedServices,
 		&stats.ActiveAlerts,
 	)
 	
@@ -464,28 +677,61 @@ func (s *Server) getStats(c *gin.Context) {
 		return
 	}
 
-	// Средний uptime за последние 24 часа
-	uptimeQuery := `
-		SELECT COALESCE(AVG(uptime), 0)
-		FROM (
-			SELECT 
-				s.id,
-				(COUNT(CASE WHEN hc.status = 'healthy' THEN 1 END) * 100.0 / COUNT(*)) as uptime
-			FROM services s
-			LEFT JOIN health_checks hc ON hc.service_id = s.id 
-				AND hc.checked_at >= NOW() - INTERVAL '24 hours'
-			GROUP BY s.id
-		) uptimes
-	`
-	
-	err = s.db.QueryRow(uptimeQuery).Scan(&stats.AverageUptime)
+	// Средний uptime за последние 24 часа по сервисам, у которых есть проверки
+	rows, err := s.db.Query(`SELECT ` + database.ServiceColumns + ` FROM services`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var services []models.Service
+	for rows.Next() {
+		var service models.Service
+		if err := database.ScanService(rows, &service); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		services = append(services, service)
+	}
+	rows.Close()
+
+	uptimes, err := s.recentUptime(services)
 	if err != nil {
-		stats.AverageUptime = 0
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sum float64
+	var measured int
+	for _, result := range uptimes {
+		if result.UpSeconds+result.DownSeconds > 0 {
+			sum += result.Uptime
+			measured++
+		}
+	}
+	if measured > 0 {
+		stats.AverageUptime = sum / float64(measured)
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
 
+func (s *Server) getClusterStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": s.elector.InstanceID(),
+		"is_leader":   s.elector.IsLeader(),
+	})
+}
+
+func (s *Server) getPoolStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.monitorService.PoolStats())
+}
+
+func (s *Server) getWriterStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.monitorService.WriterStats())
+}
+
 func (s *Server) handleWebSocket(c *gin.Context) {
 	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -494,8 +740,15 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	}
 	defer conn.Close()
 
+	s.clientsMu.Lock()
 	s.clients[conn] = true
-	defer delete(s.clients, conn)
+	s.clientsMu.Unlock()
+
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, conn)
+		s.clientsMu.Unlock()
+	}()
 
 	// Отправляем приветственное сообщение
 	welcome := map[string]interface{}{
@@ -504,7 +757,10 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		"timestamp": time.Now(),
 	}
 	
-	if err := conn.WriteJSON(welcome); err != nil {
+	s.clientsMu.Lock()
+	err = conn.WriteJSON(welcome)
+	s.clientsMu.Unlock()
+	if err != nil {
 		s.logger.Error("WebSocket write error:", err)
 		return
 	}
@@ -526,6 +782,9 @@ func (s *Server) broadcastToClients(message interface{}) {
 		return
 	}
 
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
 	for client := range s.clients {
 		if err := client.WriteMessage(websocket.TextMessage, data); err != nil {
 			s.logger.Error("WebSocket broadcast error:", err)
@@ -534,3 +793,20 @@ func (s *Server) broadcastToClients(message interface{}) {
 		}
 	}
 }
+
+// closeClients отправляет всем WebSocket клиентам close frame и закрывает соединения
+func (s *Server) closeClients() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Сервер останавливается")
+	deadline := time.Now().Add(time.Second)
+
+	for client := range s.clients {
+		if err := client.WriteControl(websocket.CloseMessage, message, deadline); err != nil {
+			s.logger.Error("WebSocket close error:", err)
+		}
+		client.Close()
+		delete(s.clients, client)
+	}
+}
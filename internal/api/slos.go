@@ -0,0 +1,168 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/slo"
+)
+
+func (s *Server) getSLOs(c *gin.Context) {
+	rows, err := s.db.Query(`SELECT ` + slo.SLOColumns + ` FROM slos ORDER BY id`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	slos := []models.SLO{}
+	for rows.Next() {
+		var item models.SLO
+		if err := slo.ScanSLO(rows, &item); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		slos = append(slos, item)
+	}
+	rows.Close()
+
+	for i := range slos {
+		status, err := s.sloEvaluator.Status(slos[i])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		slos[i].Status = status
+	}
+
+	c.JSON(http.StatusOK, slos)
+}
+
+func (s *Server) getSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	var item models.SLO
+	if err := slo.ScanSLO(s.db.QueryRow(`SELECT `+slo.SLOColumns+` FROM slos WHERE id = $1`, id), &item); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLO не найден"})
+		return
+	}
+
+	status, err := s.sloEvaluator.Status(item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	item.Status = status
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (s *Server) createSLO(c *gin.Context) {
+	req, ok := bindSLORequest(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		INSERT INTO slos (name, service_id, group_name, objective, target, latency_threshold, window_days)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + slo.SLOColumns
+
+	var item models.SLO
+	err := slo.ScanSLO(s.db.QueryRow(query, req.Name, req.ServiceID, req.Group, req.Objective, req.Target,
+		req.LatencyThreshold, req.WindowDays), &item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+func (s *Server) updateSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	req, ok := bindSLORequest(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		UPDATE slos
+		SET name = $2, service_id = $3, group_name = $4, objective = $5, target = $6,
+		    latency_threshold = $7, window_days = $8
+		WHERE id = $1
+		RETURNING ` + slo.SLOColumns
+
+	var item models.SLO
+	err = slo.ScanSLO(s.db.QueryRow(query, id, req.Name, req.ServiceID, req.Group, req.Objective, req.Target,
+		req.LatencyThreshold, req.WindowDays), &item)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLO не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (s *Server) deleteSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM slos WHERE id = $1`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLO не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLO удален"})
+}
+
+// bindSLORequest разбирает и проверяет запрос; при ошибке ответ уже отправлен
+func bindSLORequest(c *gin.Context) (models.SLORequest, bool) {
+	var req models.SLORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+
+	if (req.ServiceID == nil) == (req.Group == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нужно указать либо service_id, либо group"})
+		return req, false
+	}
+	if req.Objective == models.ObjectiveLatency && req.LatencyThreshold <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Для latency нужен latency_threshold"})
+		return req, false
+	}
+	if req.WindowDays == 0 {
+		req.WindowDays = 30
+	}
+
+	return req, true
+}
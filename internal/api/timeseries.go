@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/models"
+)
+
+// maxTimeSeriesPoints ограничение на количество интервалов в одном ответе
+const maxTimeSeriesPoints = 1000
+
+func (s *Server) getServiceTimeSeries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	period, err := parseWindow(c.DefaultQuery("period", "24h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный period: " + err.Error()})
+		return
+	}
+
+	step, err := parseWindow(c.DefaultQuery("step", "1h"))
+	if err != nil || step < time.Minute {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный step: минимум 1m"})
+		return
+	}
+
+	if period/step > maxTimeSeriesPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Слишком много интервалов, максимум %d", maxTimeSeriesPoints)})
+		return
+	}
+
+	query := `
+		SELECT to_timestamp(floor(extract(epoch FROM checked_at) / $3) * $3) AS bucket,
+		       COUNT(*),
+		       COUNT(CASE WHEN status = 'healthy' THEN 1 END),
+		       COALESCE(AVG(response_time), 0),
+		       COALESCE(AVG(dns_time), 0),
+		       COALESCE(AVG(connect_time), 0),
+		       COALESCE(AVG(tls_time), 0),
+		       COALESCE(AVG(ttfb), 0),
+		       COALESCE(AVG(transfer_time), 0)
+		FROM health_checks
+		WHERE service_id = $1
+		  AND checked_at >= NOW() - $2 * INTERVAL '1 second'
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := s.db.Query(query, id, int64(period.Seconds()), int64(step.Seconds()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	points := []models.TimeSeriesPoint{}
+	for rows.Next() {
+		var p models.TimeSeriesPoint
+		err := rows.Scan(
+			&p.Time,
+			&p.Checks,
+			&p.HealthyChecks,
+			&p.AvgResponseTime,
+			&p.Timings.DNS,
+			&p.Timings.Connect,
+			&p.Timings.TLS,
+			&p.Timings.TTFB,
+			&p.Timings.Transfer,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		points = append(points, p)
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// parseWindow разбирает длительность вида 30m, 24h или 7d
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("неверное количество дней %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("длительность должна быть положительной")
+	}
+	return d, nil
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/uptime"
+)
+
+// uptimeWindows окна, которые возвращаются, если окно не указано явно
+var uptimeWindows = []string{"24h", "7d", "30d", "90d"}
+
+// maxUptimeRange наибольший период uptime: расчет читает всю историю
+// проверок за период
+const maxUptimeRange = 90 * 24 * time.Hour
+
+// getServiceUptime возвращает uptime сервиса за окно window (24h, 7d, ...),
+// за произвольный период from/to в RFC3339 или за все стандартные окна
+func (s *Server) getServiceUptime(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	service, err := s.db.GetService(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сервис не найден"})
+		return
+	}
+
+	now := time.Now()
+
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, err := time.Parse(time.RFC3339, c.Query("from"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный from: ожидается RFC3339"})
+			return
+		}
+		to := now
+		if c.Query("to") != "" {
+			if to, err = time.Parse(time.RFC3339, c.Query("to")); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный to: ожидается RFC3339"})
+				return
+			}
+		}
+		if !to.After(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to должен быть позже from"})
+			return
+		}
+		if to.Sub(from) > maxUptimeRange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Период from/to не должен превышать 90 дней"})
+			return
+		}
+
+		result, err := s.uptime.ForService(*service, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if value := c.Query("window"); value != "" {
+		window, err := parseWindow(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный window: " + err.Error()})
+			return
+		}
+		if window > maxUptimeRange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window не должен превышать 90 дней"})
+			return
+		}
+
+		result, err := s.uptime.ForService(*service, now.Add(-window), now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	results := make(map[string]uptime.Result, len(uptimeWindows))
+	for _, value := range uptimeWindows {
+		window, _ := parseWindow(value)
+		result, err := s.uptime.ForService(*service, now.Add(-window), now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		results[value] = result
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// recentUptime uptime сервисов за последние 24 часа для списка и статистики
+// по ID сервиса; история и окна обслуживания загружаются одним запросом каждое
+func (s *Server) recentUptime(services []models.Service) (map[int]uptime.Result, error) {
+	now := time.Now()
+	return s.uptime.ForServices(services, now.Add(-24*time.Hour), now)
+}
+
+func (s *Server) getMaintenanceWindows(c *gin.Context) {
+	query := `
+		SELECT id, service_id, starts_at, ends_at, reason, created_at
+		FROM maintenance_windows
+		WHERE ends_at > NOW() - $1 * INTERVAL '1 second'
+		ORDER BY starts_at DESC
+	`
+
+	// По умолчанию только текущие и будущие окна, ?period=90d - и прошедшие
+	var period time.Duration
+	if value := c.Query("period"); value != "" {
+		var err error
+		if period, err = parseWindow(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный period: " + err.Error()})
+			return
+		}
+	}
+
+	rows, err := s.db.Query(query, int64(period.Seconds()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	windows := []models.MaintenanceWindow{}
+	for rows.Next() {
+		var w models.MaintenanceWindow
+		var serviceID sql.NullInt64
+		if err := rows.Scan(&w.ID, &serviceID, &w.StartsAt, &w.EndsAt, &w.Reason, &w.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if serviceID.Valid {
+			id := int(serviceID.Int64)
+			w.ServiceID = &id
+		}
+		windows = append(windows, w)
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+func (s *Server) createMaintenanceWindow(c *gin.Context) {
+	var req models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at должен быть позже starts_at"})
+		return
+	}
+
+	if req.ServiceID != nil {
+		if _, err := s.db.GetService(*req.ServiceID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Сервис не найден"})
+			return
+		}
+	}
+
+	query := `
+		INSERT INTO maintenance_windows (service_id, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	w := models.MaintenanceWindow{
+		ServiceID: req.ServiceID,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		Reason:    req.Reason,
+	}
+
+	err := s.db.QueryRow(query, req.ServiceID, req.StartsAt, req.EndsAt, req.Reason).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, w)
+}
+
+func (s *Server) deleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID"})
+		return
+	}
+
+	result, err := s.db.Exec(`DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Окно обслуживания не найдено"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Окно обслуживания удалено"})
+}
@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"time"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+)
+
+// leaderLockKey ключ advisory lock, которым владеет лидер
+const leaderLockKey int64 = 0x736d6c6561646572
+
+// Elector выбирает лидера среди экземпляров через pg_try_advisory_lock.
+// Блокировка удерживается на выделенном соединении: если экземпляр падает,
+// Postgres закрывает его сессию и блокировку получает другой экземпляр
+type Elector struct {
+	db         *database.DB
+	logger     *logger.Logger
+	instanceID string
+	interval   time.Duration
+	leader     atomic.Bool
+}
+
+func NewElector(db *database.DB, logger *logger.Logger, instanceID string, interval time.Duration) *Elector {
+	return &Elector{
+		db:         db,
+		logger:     logger,
+		instanceID: instanceID,
+		interval:   interval,
+	}
+}
+
+// InstanceID идентификатор текущего экземпляра
+func (e *Elector) InstanceID() string {
+	return e.instanceID
+}
+
+// IsLeader сообщает, удерживает ли экземпляр лидерство
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run пытается стать лидером каждые interval и, пока лидерство удерживается,
+// выполняет lead. Контекст lead отменяется при потере лидерства или отмене ctx;
+// Run возвращается только после завершения lead
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		conn, err := e.tryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.logger.Error("Ошибка захвата лидерства:", err)
+		}
+
+		if conn != nil {
+			e.lead(ctx, conn, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryAcquire возвращает соединение с захваченной блокировкой или nil
+func (e *Elector) tryAcquire(ctx context.Context) (*sql.Conn, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, leaderLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// lead выполняет работу лидера, пока соединение с блокировкой живо
+func (e *Elector) lead(ctx context.Context, conn *sql.Conn, lead func(ctx context.Context)) {
+	e.logger.Infof("Экземпляр %s стал лидером", e.instanceID)
+	e.leader.Store(true)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	lost := false
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-ticker.C:
+			// Потеря соединения означает, что блокировка могла перейти к другому экземпляру
+			if err := e.ping(ctx, conn); err != nil && ctx.Err() == nil {
+				e.logger.Error("Потеряно соединение с блокировкой лидера:", err)
+				lost = true
+				break loop
+			}
+		}
+	}
+
+	e.leader.Store(false)
+	cancel()
+	<-done
+
+	if lost {
+		// На соединении может висеть ping, поэтому блокировку не освобождаем:
+		// ее держит сессия, которая уже считается потерянной. Соединение
+		// закрываем в фоне, когда ping вернется, и не возвращаем в пул
+		go func() {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			conn.Close()
+		}()
+		e.logger.Infof("Экземпляр %s больше не лидер", e.instanceID)
+		return
+	}
+
+	// Отдельный контекст: ctx уже может быть отменен при остановке
+	unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer unlockCancel()
+	if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, leaderLockKey); err != nil {
+		e.logger.Error("Ошибка освобождения блокировки лидера:", err)
+
+		// Сессия с блокировкой не должна вернуться в пул, закрываем соединение
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	conn.Close()
+
+	e.logger.Infof("Экземпляр %s больше не лидер", e.instanceID)
+}
+
+// ping проверяет соединение с блокировкой не дольше интервала попыток.
+// На полуоткрытом TCP соединении запрос не завершается и после отмены,
+// поэтому ping не дожидается его: за это время Postgres мог освободить
+// блокировку, и лидером стал другой экземпляр
+func (e *Elector) ping(ctx context.Context, conn *sql.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- conn.PingContext(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -8,6 +9,9 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// migrationLockKey ключ advisory lock на время миграций
+const migrationLockKey int64 = 0x736d6d6967726174
+
 type DB struct {
 	*sql.DB
 }
@@ -75,10 +79,272 @@ func Migrate(db *DB) error {
 	CREATE INDEX IF NOT EXISTS idx_alerts_is_resolved ON alerts(is_resolved);
 	`
 
-	queries := []string{createServicesTable, createChecksTable, createAlertsTable, createIndexes}
+	// Настройки проверки сервиса
+	alterServicesProbeConfig := `
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS probe_config JSONB NOT NULL DEFAULT '{}';
+	`
+
+	// Разбивка времени HTTP проверки по фазам (мс)
+	alterChecksTimings := `
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS dns_time DOUBLE PRECISION;
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS connect_time DOUBLE PRECISION;
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS tls_time DOUBLE PRECISION;
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS ttfb DOUBLE PRECISION;
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS transfer_time DOUBLE PRECISION;
+	`
+
+	// Пороги деградации и тип алерта
+	alterDegraded := `
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS degraded_threshold INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS degraded_p95_threshold INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS kind VARCHAR(50) NOT NULL DEFAULT 'down';
+	`
+
+	// Не больше одного активного алерта с одним отпечатком, даже если
+	// проверки выполняют несколько экземпляров. Старые дубли разрешаются
+	createActiveAlertIndex := `
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(255);
+	UPDATE alerts SET fingerprint = 'service:' || service_id || ':' || kind WHERE fingerprint IS NULL;
+	ALTER TABLE alerts ALTER COLUMN fingerprint SET NOT NULL;
+	UPDATE alerts SET is_resolved = true, resolved_at = CURRENT_TIMESTAMP
+	WHERE is_resolved = false AND id NOT IN (
+		SELECT MAX(id) FROM alerts WHERE is_resolved = false GROUP BY fingerprint
+	);
+	DROP INDEX IF EXISTS idx_alerts_active_kind;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_active_fingerprint ON alerts(fingerprint) WHERE is_resolved = false;
+	`
+
+	// Проверки из нескольких локаций и итоговый статус сервиса по кворуму
+	alterLocations := `
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS location VARCHAR(100) NOT NULL DEFAULT 'local';
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'unknown';
+	CREATE INDEX IF NOT EXISTS idx_health_checks_service_location ON health_checks(service_id, location, checked_at);
+	CREATE TABLE IF NOT EXISTS agents (
+		location VARCHAR(100) PRIMARY KEY,
+		last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	// Группы сервисов и цели надежности (SLO)
+	createSLOTable := `
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS group_name VARCHAR(255) NOT NULL DEFAULT '';
+	CREATE TABLE IF NOT EXISTS slos (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
+		group_name VARCHAR(255) NOT NULL DEFAULT '',
+		objective VARCHAR(50) NOT NULL,
+		target DOUBLE PRECISION NOT NULL,
+		latency_threshold INTEGER NOT NULL DEFAULT 0,
+		window_days INTEGER NOT NULL DEFAULT 30,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS slo_id INTEGER REFERENCES slos(id) ON DELETE CASCADE;
+	CREATE INDEX IF NOT EXISTS idx_services_group_name ON services(group_name);
+	`
+
+	// Итоговый статус сервиса на момент проверки и окна обслуживания
+	createMaintenanceTable := `
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS service_status VARCHAR(50);
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id SERIAL PRIMARY KEY,
+		service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
+		starts_at TIMESTAMP NOT NULL,
+		ends_at TIMESTAMP NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_maintenance_windows_period ON maintenance_windows(starts_at, ends_at);
+	`
+
+	// Каналы оповещений, дежурства и политики эскалации
+	createEscalationTables := `
+	CREATE TABLE IF NOT EXISTS channels (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL UNIQUE,
+		type VARCHAR(50) NOT NULL,
+		config JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS responders (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL UNIQUE,
+		channel_id INTEGER NOT NULL REFERENCES channels(id) ON DELETE RESTRICT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS schedules (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL UNIQUE,
+		rotation VARCHAR(50) NOT NULL,
+		starts_at TIMESTAMP NOT NULL,
+		participants INTEGER[] NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS schedule_overrides (
+		id SERIAL PRIMARY KEY,
+		schedule_id INTEGER NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+		responder_id INTEGER NOT NULL REFERENCES responders(id) ON DELETE CASCADE,
+		starts_at TIMESTAMP NOT NULL,
+		ends_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL UNIQUE,
+		levels JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS escalation_policy_id INTEGER REFERENCES escalation_policies(id) ON DELETE SET NULL;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS acknowledged_by VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS escalation_level INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS resolve_notified BOOLEAN NOT NULL DEFAULT false;
+	CREATE INDEX IF NOT EXISTS idx_schedule_overrides_schedule ON schedule_overrides(schedule_id, starts_at, ends_at);
+	`
+
+	// Очередь оповещений и журнал попыток доставки
+	createOutboxTables := `
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		id SERIAL PRIMARY KEY,
+		alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+		channel_id INTEGER NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+		recipient VARCHAR(255) NOT NULL DEFAULT '',
+		event VARCHAR(50) NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(50) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS notification_attempts (
+		id SERIAL PRIMARY KEY,
+		outbox_id INTEGER NOT NULL REFERENCES notification_outbox(id) ON DELETE CASCADE,
+		attempt INTEGER NOT NULL,
+		attempted_at TIMESTAMP NOT NULL,
+		duration_ms BIGINT NOT NULL DEFAULT 0,
+		status_code INTEGER,
+		error TEXT NOT NULL DEFAULT '',
+		success BOOLEAN NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_alert ON notification_outbox(alert_id);
+	CREATE INDEX IF NOT EXISTS idx_notification_attempts_outbox ON notification_attempts(outbox_id);
+	`
+
+	// Пользовательские правила алертов
+	createRulesTable := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
+		group_name VARCHAR(255) NOT NULL DEFAULT '',
+		metric VARCHAR(50) NOT NULL,
+		operator VARCHAR(2) NOT NULL,
+		threshold DOUBLE PRECISION NOT NULL,
+		window_seconds INTEGER NOT NULL DEFAULT 300,
+		for_seconds INTEGER NOT NULL DEFAULT 0,
+		severity VARCHAR(50) NOT NULL,
+		labels JSONB NOT NULL DEFAULT '{}',
+		channels INTEGER[] NOT NULL DEFAULT '{}',
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS rule_id INTEGER REFERENCES alert_rules(id) ON DELETE CASCADE;
+	ALTER TABLE alerts ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+	`
+
+	// Метки сервисов и дерево маршрутизации алертов
+	createRoutingTables := `
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+	CREATE TABLE IF NOT EXISTS routing_tree (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		tree JSONB NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS route_notifications (
+		alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+		route_path VARCHAR(255) NOT NULL,
+		channels INTEGER[] NOT NULL,
+		last_notified_at TIMESTAMP NOT NULL,
+		resolve_notified BOOLEAN NOT NULL DEFAULT false,
+		PRIMARY KEY (alert_id, route_path)
+	);
+	`
+
+	// Группировка оповещений и дайджесты: у дайджеста нет одного алерта
+	alterDigests := `
+	ALTER TABLE notification_outbox ALTER COLUMN alert_id DROP NOT NULL;
+	ALTER TABLE channels ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP;
+	`
+
+	// Эталонные ответы DNS проверок по локациям
+	createDNSBaselines := `
+	CREATE TABLE IF NOT EXISTS dns_baselines (
+		service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+		location VARCHAR(255) NOT NULL,
+		answers TEXT[] NOT NULL,
+		last_answers TEXT[] NOT NULL,
+		changed_at TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (service_id, location)
+	);
+	`
+
+	// Потери пакетов ping проверок
+	alterPacketLoss := `
+	ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS packet_loss DOUBLE PRECISION;
+	`
+
+	// Зашифрованные секреты: nonce и шифротекст AES-GCM
+	createSecrets := `
+	CREATE TABLE IF NOT EXISTS secrets (
+		name VARCHAR(100) PRIMARY KEY,
+		value BYTEA NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	queries := []string{
+		createServicesTable,
+		createChecksTable,
+		createAlertsTable,
+		createIndexes,
+		alterServicesProbeConfig,
+		alterChecksTimings,
+		alterDegraded,
+		createActiveAlertIndex,
+		alterLocations,
+		createSLOTable,
+		createMaintenanceTable,
+		createEscalationTables,
+		createOutboxTables,
+		createRulesTable,
+		createRoutingTables,
+		alterDigests,
+		createDNSBaselines,
+		alterPacketLoss,
+		createSecrets,
+	}
+
+	// Экземпляры, стартующие одновременно, выполняют миграции по очереди
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка миграции: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("ошибка блокировки миграции: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
 
 	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
 			return fmt.Errorf("ошибка миграции: %w", err)
 		}
 	}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"service-monitor/internal/models"
+)
+
+// ServiceColumns список колонок services в порядке, который ожидает ScanService
+const ServiceColumns = `id, name, url, group_name, check_interval, timeout, probe_config,
+	degraded_threshold, degraded_p95_threshold, escalation_policy_id, labels, created_at, updated_at`
+
+// Scanner общий интерфейс для *sql.Row и *sql.Rows
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ServiceFields возвращает указатели на поля сервиса в порядке ServiceColumns,
+// чтобы к ним можно было добавить дополнительные колонки запроса
+func ServiceFields(service *models.Service) []interface{} {
+	return []interface{}{
+		&service.ID,
+		&service.Name,
+		&service.URL,
+		&service.Group,
+		&service.CheckInterval,
+		&service.Timeout,
+		&service.ProbeConfig,
+		&service.DegradedThreshold,
+		&service.DegradedP95Threshold,
+		&service.EscalationPolicyID,
+		&service.Labels,
+		&service.CreatedAt,
+		&service.UpdatedAt,
+	}
+}
+
+// ScanService читает строку, выбранную через ServiceColumns
+func ScanService(row Scanner, service *models.Service) error {
+	return row.Scan(ServiceFields(service)...)
+}
+
+// GetService загружает сервис по ID
+func (db *DB) GetService(id int) (*models.Service, error) {
+	query := `SELECT ` + ServiceColumns + ` FROM services WHERE id = $1`
+
+	var service models.Service
+	if err := ScanService(db.QueryRow(query, id), &service); err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
@@ -1,21 +1,246 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
 // Service представляет сервис для мониторинга
 type Service struct {
-	ID            int       `json:"id" db:"id"`
-	Name          string    `json:"name" db:"name"`
-	URL           string    `json:"url" db:"url"`
-	CheckInterval int       `json:"check_interval" db:"check_interval"`
-	Timeout       int       `json:"timeout" db:"timeout"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
-	LastStatus    string    `json:"last_status,omitempty"`
-	LastCheck     time.Time `json:"last_check,omitempty"`
-	Uptime        float64   `json:"uptime,omitempty"`
+	ID            int         `json:"id" db:"id"`
+	Name          string      `json:"name" db:"name"`
+	URL           string      `json:"url" db:"url"`
+	Group         string      `json:"group" db:"group_name"`
+	CheckInterval int         `json:"check_interval" db:"check_interval"`
+	Timeout       int         `json:"timeout" db:"timeout"`
+	ProbeConfig   ProbeConfig `json:"probe_config" db:"probe_config"`
+	// Пороги деградации в мс: время одного ответа и p95 по последним проверкам, 0 - выключено
+	DegradedThreshold    int `json:"degraded_threshold" db:"degraded_threshold"`
+	DegradedP95Threshold int `json:"degraded_p95_threshold" db:"degraded_p95_threshold"`
+	// EscalationPolicyID политика оповещения об алертах сервиса, nil - без оповещений
+	EscalationPolicyID *int `json:"escalation_policy_id,omitempty" db:"escalation_policy_id"`
+	// Labels метки для маршрутизации алертов
+	Labels     Labels    `json:"labels" db:"labels"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	LastStatus string    `json:"last_status,omitempty"`
+	LastCheck  time.Time `json:"last_check,omitempty"`
+	Uptime     float64   `json:"uptime,omitempty"`
+}
+
+// ProbeConfig настройки проверки сервиса, хранятся в services.probe_config (JSONB)
+type ProbeConfig struct {
+	// Type тип проверки: http (по умолчанию), scenario, dns, icmp, udp, grpc, postgres, mysql или redis
+	Type           string            `json:"type,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	ExpectedStatus []int             `json:"expected_status,omitempty"`
+	BodyContains   string            `json:"body_contains,omitempty"`
+
+	// Auth и TLS применяются к HTTP проверкам и сценариям
+	Auth *AuthConfig `json:"auth,omitempty"`
+	TLS  *TLSConfig  `json:"tls,omitempty"`
+
+	Network *NetworkConfig `json:"network,omitempty"`
+
+	DNS  *DNSProbeConfig  `json:"dns,omitempty"`
+	ICMP *ICMPProbeConfig `json:"icmp,omitempty"`
+	UDP  *UDPProbeConfig  `json:"udp,omitempty"`
+	GRPC *GRPCProbeConfig `json:"grpc,omitempty"`
+
+	Database *DatabaseProbeConfig `json:"database,omitempty"`
+
+	Scenario *ScenarioProbeConfig `json:"scenario,omitempty"`
+}
+
+// ProbeType типы проверок
+const (
+	ProbeHTTP = "http"
+	ProbeDNS  = "dns"
+	ProbeICMP = "icmp"
+	ProbeUDP  = "udp"
+	ProbeGRPC = "grpc"
+
+	ProbePostgres = "postgres"
+	ProbeMySQL    = "mysql"
+	ProbeRedis    = "redis"
+
+	ProbeScenario = "scenario"
+)
+
+// NetworkConfig сетевые настройки проверки; пустые поля берутся из настроек
+// по умолчанию (PROBE_PROXY, PROBE_RESOLVER, PROBE_IP_VERSION)
+type NetworkConfig struct {
+	// Proxy http://, https://, socks5:// или socks5h:// с учетными данными в URL;
+	// direct - без прокси, даже если задан прокси по умолчанию.
+	// Через прокси работают TCP проверки, адрес сервиса разрешает прокси
+	Proxy string `json:"proxy,omitempty"`
+	// Resolver DNS сервер host[:port] для разрешения адреса сервиса
+	Resolver string `json:"resolver,omitempty"`
+	// IPVersion 4 или 6 - подключаться только по этой версии IP; 0 - любая
+	IPVersion int `json:"ip_version,omitempty"`
+}
+
+// ProxyDirect отключает прокси по умолчанию для сервиса
+const ProxyDirect = "direct"
+
+// AuthConfig аутентификация HTTP проверки
+type AuthConfig struct {
+	// Type basic, bearer или oauth2
+	Type     string        `json:"type"`
+	Username string        `json:"username,omitempty"`
+	Password string        `json:"password,omitempty"`
+	Token    string        `json:"token,omitempty"`
+	OAuth2   *OAuth2Config `json:"oauth2,omitempty"`
+}
+
+// AuthType способы аутентификации HTTP проверок
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+// OAuth2Config получение токена по client credentials; токен кэшируется
+// до истечения срока действия
+type OAuth2Config struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// TLSConfig настройки TLS HTTP проверки. Сертификаты и ключ задаются в PEM
+type TLSConfig struct {
+	// CACert сертификаты CA для проверки сервера вместо системных
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert и ClientKey клиентский сертификат для mTLS
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// InsecureSkipVerify не проверять сертификат сервера (тестовые стенды)
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// DNSProbeConfig настройки DNS проверки; имя для запроса берется из URL сервиса
+type DNSProbeConfig struct {
+	// RecordType A (по умолчанию), AAAA, CNAME, MX, TXT или SRV
+	RecordType string `json:"record_type,omitempty"`
+	// Resolver адрес DNS сервера host[:port]; пустой - системный резолвер
+	Resolver string `json:"resolver,omitempty"`
+	// Expected ожидаемый набор ответов без учета порядка; пустой - любой ответ
+	Expected []string `json:"expected,omitempty"`
+	// AlertOnChange открывать алерт, когда ответ отличается от эталонного
+	AlertOnChange bool `json:"alert_on_change,omitempty"`
+}
+
+// ICMPProbeConfig настройки ping проверки; адрес хоста берется из URL сервиса
+type ICMPProbeConfig struct {
+	// Count сколько эхо-запросов отправить, по умолчанию 3
+	Count int `json:"count,omitempty"`
+	// IntervalMs пауза между запросами, по умолчанию 200 мс
+	IntervalMs int `json:"interval_ms,omitempty"`
+	// MaxLossPercent допустимая потеря пакетов; 0 - неуспешна только полная потеря
+	MaxLossPercent float64 `json:"max_loss_percent,omitempty"`
+}
+
+// UDPProbeConfig настройки UDP проверки: отправить датаграмму на host:port
+// из URL сервиса и дождаться ответа. Данные задаются текстом или в hex
+type UDPProbeConfig struct {
+	Send      string `json:"send,omitempty"`
+	SendHex   string `json:"send_hex,omitempty"`
+	Expect    string `json:"expect,omitempty"`     // подстрока ответа; пусто - любой ответ
+	ExpectHex string `json:"expect_hex,omitempty"` // начало ответа в hex
+}
+
+// GRPCProbeConfig настройки проверки по протоколу grpc.health.v1;
+// адрес host:port берется из URL сервиса
+type GRPCProbeConfig struct {
+	// Service имя сервиса в запросе Check; пустое - состояние сервера в целом
+	Service  string            `json:"service,omitempty"`
+	TLS      bool              `json:"tls,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DatabaseProbeConfig настройки проверки Postgres, MySQL или Redis;
+// строка подключения берется из URL сервиса
+type DatabaseProbeConfig struct {
+	// Query запрос Postgres или MySQL, по умолчанию SELECT 1;
+	// результатом считается первая колонка первой строки
+	Query string `json:"query,omitempty"`
+	// Command команда Redis, по умолчанию PING
+	Command string `json:"command,omitempty"`
+	// Expected ожидаемый результат; для Redis INFO - строка вида role:master
+	Expected string `json:"expected,omitempty"`
+	// MaxReplicationLagSeconds допустимое отставание реплики, 0 - не проверяется
+	MaxReplicationLagSeconds float64 `json:"max_replication_lag_seconds,omitempty"`
+}
+
+// ScenarioProbeConfig сценарий из нескольких HTTP запросов с общими cookie.
+// В URL, заголовках и теле шага {{имя}} заменяется значением переменной,
+// извлеченной на одном из предыдущих шагов
+type ScenarioProbeConfig struct {
+	Steps []ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep шаг сценария; URL может быть относительным URL сервиса
+type ScenarioStep struct {
+	Name           string            `json:"name"`
+	Method         string            `json:"method,omitempty"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	ExpectedStatus []int             `json:"expected_status,omitempty"`
+	BodyContains   string            `json:"body_contains,omitempty"`
+	Extract        []Extraction      `json:"extract,omitempty"`
+}
+
+// Extraction переменная из ответа шага: из поля JSON (путь через точку,
+// индексы массивов числами, например data.items.0.id) или из заголовка
+type Extraction struct {
+	Name   string `json:"name"`
+	JSON   string `json:"json,omitempty"`
+	Header string `json:"header,omitempty"`
+}
+
+// RecordType типы записей DNS проверки
+const (
+	RecordA     = "A"
+	RecordAAAA  = "AAAA"
+	RecordCNAME = "CNAME"
+	RecordMX    = "MX"
+	RecordTXT   = "TXT"
+	RecordSRV   = "SRV"
+)
+
+// DNSBaseline эталонный ответ DNS проверки из локации
+type DNSBaseline struct {
+	ServiceID   int        `json:"service_id"`
+	Location    string     `json:"location"`
+	Answers     []string   `json:"answers"`
+	LastAnswers []string   `json:"last_answers"`
+	ChangedAt   *time.Time `json:"changed_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Value сериализует настройки для записи в БД
+func (p ProbeConfig) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan читает настройки из БД
+func (p *ProbeConfig) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = ProbeConfig{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return errors.New("неподдерживаемый тип probe_config")
 }
 
 // HealthCheck представляет результат проверки здоровья сервиса
@@ -26,56 +251,570 @@ type HealthCheck struct {
 	ResponseTime int       `json:"response_time" db:"response_time"`
 	ErrorMessage string    `json:"error_message" db:"error_message"`
 	CheckedAt    time.Time `json:"checked_at" db:"checked_at"`
+	Location     string    `json:"location" db:"location"`
+	Timings      *Timings  `json:"timings,omitempty"`
+	// ServiceStatus итоговый статус сервиса по кворуму после этой проверки
+	ServiceStatus string `json:"service_status,omitempty" db:"service_status"`
+	// PacketLoss процент потерянных пакетов, только для ping проверок
+	PacketLoss *float64 `json:"packet_loss,omitempty" db:"packet_loss"`
+}
+
+// Agent агент проверок в удаленной локации
+type Agent struct {
+	Location   string    `json:"location" db:"location"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// Timings разбивка времени HTTP проверки по фазам, в миллисекундах.
+// TTFB считается от отправки запроса до первого байта ответа,
+// поэтому сумма фаз примерно равна response_time
+type Timings struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	TTFB     float64 `json:"ttfb"`
+	Transfer float64 `json:"transfer"`
+}
+
+// TimeSeriesPoint агрегат проверок сервиса за один интервал
+type TimeSeriesPoint struct {
+	Time            time.Time `json:"time"`
+	Checks          int       `json:"checks"`
+	HealthyChecks   int       `json:"healthy_checks"`
+	AvgResponseTime float64   `json:"avg_response_time"`
+	Timings         Timings   `json:"timings"`
 }
 
 // Alert представляет алерт о проблеме с сервисом
 type Alert struct {
-	ID         int       `json:"id" db:"id"`
-	ServiceID  int       `json:"service_id" db:"service_id"`
-	Message    string    `json:"message" db:"message"`
-	Severity   string    `json:"severity" db:"severity"`
-	IsResolved bool      `json:"is_resolved" db:"is_resolved"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
-	Service    *Service  `json:"service,omitempty"`
+	ID          int        `json:"id" db:"id"`
+	ServiceID   int        `json:"service_id" db:"service_id"`
+	SLOID       *int       `json:"slo_id,omitempty" db:"slo_id"`
+	RuleID      *int       `json:"rule_id,omitempty" db:"rule_id"`
+	Message     string     `json:"message" db:"message"`
+	Severity    string     `json:"severity" db:"severity"`
+	Kind        string     `json:"kind" db:"kind"`
+	Fingerprint string     `json:"fingerprint" db:"fingerprint"`
+	Labels      Labels     `json:"labels,omitempty" db:"labels"`
+	IsResolved  bool       `json:"is_resolved" db:"is_resolved"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at" db:"resolved_at"`
+	// Подтверждение останавливает эскалацию
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	// EscalationLevel последний оповещенный уровень политики, 0 - еще никто
+	EscalationLevel int      `json:"escalation_level" db:"escalation_level"`
+	Service         *Service `json:"service,omitempty"`
 }
 
 // CreateServiceRequest запрос на создание сервиса
 type CreateServiceRequest struct {
-	Name          string `json:"name" binding:"required"`
-	URL           string `json:"url" binding:"required"`
-	CheckInterval int    `json:"check_interval"`
-	Timeout       int    `json:"timeout"`
+	Name          string      `json:"name" binding:"required"`
+	URL           string      `json:"url" binding:"required"`
+	Group         string      `json:"group"`
+	CheckInterval int         `json:"check_interval"`
+	Timeout       int         `json:"timeout"`
+	ProbeConfig   ProbeConfig `json:"probe_config"`
+
+	DegradedThreshold    int `json:"degraded_threshold"`
+	DegradedP95Threshold int `json:"degraded_p95_threshold"`
+
+	EscalationPolicyID *int `json:"escalation_policy_id"`
+
+	Labels Labels `json:"labels"`
 }
 
 // UpdateServiceRequest запрос на обновление сервиса
 type UpdateServiceRequest struct {
-	Name          string `json:"name"`
-	URL           string `json:"url"`
-	CheckInterval int    `json:"check_interval"`
-	Timeout       int    `json:"timeout"`
+	Name          string       `json:"name"`
+	URL           string       `json:"url"`
+	Group         *string      `json:"group"`
+	CheckInterval int          `json:"check_interval"`
+	Timeout       int          `json:"timeout"`
+	ProbeConfig   *ProbeConfig `json:"probe_config"`
+
+	DegradedThreshold    *int `json:"degraded_threshold"`
+	DegradedP95Threshold *int `json:"degraded_p95_threshold"`
+
+	// EscalationPolicyID 0 снимает политику с сервиса
+	EscalationPolicyID *int `json:"escalation_policy_id"`
+
+	Labels *Labels `json:"labels"`
+}
+
+// ProbeTestRequest запрос на пробную проверку без сохранения
+type ProbeTestRequest struct {
+	URL         string      `json:"url" binding:"required"`
+	Timeout     int         `json:"timeout"`
+	ProbeConfig ProbeConfig `json:"probe_config"`
 }
 
 // DashboardStats статистика для дашборда
 type DashboardStats struct {
-	TotalServices    int     `json:"total_services"`
-	HealthyServices  int     `json:"healthy_services"`
-	UnhealthyServices int    `json:"unhealthy_services"`
-	AverageUptime    float64 `json:"average_uptime"`
-	ActiveAlerts     int     `json:"active_alerts"`
+	TotalServices     int     `json:"total_services"`
+	HealthyServices   int     `json:"healthy_services"`
+	UnhealthyServices int     `json:"unhealthy_services"`
+	DegradedServices  int     `json:"degraded_services"`
+	AverageUptime     float64 `json:"average_uptime"`
+	ActiveAlerts      int     `json:"active_alerts"`
 }
 
 // ServiceStatus статус сервиса
 const (
 	StatusHealthy   = "healthy"
 	StatusUnhealthy = "unhealthy"
+	StatusDegraded  = "degraded"
 	StatusUnknown   = "unknown"
 )
 
+// AlertKind причина алерта
+const (
+	AlertKindDown     = "down"
+	AlertKindDegraded = "degraded"
+	AlertKindFlapping = "flapping"
+	AlertKindFastBurn = "slo_fast_burn"
+	AlertKindSlowBurn = "slo_slow_burn"
+	AlertKindRule     = "rule"
+	// AlertKindDNSChange ответ DNS отличается от эталонного
+	AlertKindDNSChange = "dns_change"
+)
+
+// SLO цель надежности для сервиса или группы сервисов
+type SLO struct {
+	ID        int    `json:"id" db:"id"`
+	Name      string `json:"name" db:"name"`
+	ServiceID *int   `json:"service_id,omitempty" db:"service_id"`
+	Group     string `json:"group,omitempty" db:"group_name"`
+	// Objective availability - доля успешных проверок,
+	// latency - доля успешных проверок быстрее LatencyThreshold
+	Objective        string     `json:"objective" db:"objective"`
+	Target           float64    `json:"target" db:"target"` // в процентах, например 99.9
+	LatencyThreshold int        `json:"latency_threshold,omitempty" db:"latency_threshold"`
+	WindowDays       int        `json:"window_days" db:"window_days"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	Status           *SLOStatus `json:"status,omitempty"`
+}
+
+// SLOStatus текущее состояние SLO и бюджета ошибок
+type SLOStatus struct {
+	TotalChecks     int     `json:"total_checks"`
+	GoodChecks      int     `json:"good_checks"`
+	SLI             float64 `json:"sli"`              // фактический процент за окно
+	BudgetRemaining float64 `json:"budget_remaining"` // доля оставшегося бюджета, может быть < 0
+	// BurnRates скорость расхода бюджета по окнам: 1 - расход ровно к концу окна SLO
+	BurnRates map[string]float64 `json:"burn_rates"`
+}
+
+// SLORequest запрос на создание или изменение SLO
+type SLORequest struct {
+	Name             string  `json:"name" binding:"required"`
+	ServiceID        *int    `json:"service_id"`
+	Group            string  `json:"group"`
+	Objective        string  `json:"objective" binding:"required,oneof=availability latency"`
+	Target           float64 `json:"target" binding:"required,gt=0,lt=100"`
+	LatencyThreshold int     `json:"latency_threshold"`
+	WindowDays       int     `json:"window_days" binding:"min=0,max=90"`
+}
+
+// SLOObjective цели SLO: доступность или время ответа
+const (
+	ObjectiveAvailability = "availability"
+	ObjectiveLatency      = "latency"
+)
+
 // AlertSeverity уровни важности алертов
 const (
-	SeverityInfo    = "info"
-	SeverityWarning = "warning"
-	SeverityError   = "error"
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
 	SeverityCritical = "critical"
 )
+
+// MaintenanceWindow плановое обслуживание; время внутри окна не учитывается
+// в uptime. Окно без ServiceID действует на все сервисы
+type MaintenanceWindow struct {
+	ID        int       `json:"id" db:"id"`
+	ServiceID *int      `json:"service_id,omitempty" db:"service_id"`
+	StartsAt  time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" db:"ends_at"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// MaintenanceWindowRequest запрос на создание окна обслуживания
+type MaintenanceWindowRequest struct {
+	ServiceID *int      `json:"service_id"`
+	StartsAt  time.Time `json:"starts_at" binding:"required"`
+	EndsAt    time.Time `json:"ends_at" binding:"required"`
+	Reason    string    `json:"reason"`
+}
+
+// Channel канал доставки оповещений
+type Channel struct {
+	ID        int           `json:"id" db:"id"`
+	Name      string        `json:"name" db:"name"`
+	Type      string        `json:"type" db:"type"`
+	Config    ChannelConfig `json:"config" db:"config"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+}
+
+// ChannelConfig настройки канала, хранятся в channels.config (JSONB)
+type ChannelConfig struct {
+	// для webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// для email
+	To []string `json:"to,omitempty"`
+	// Шаблоны text/template; пустые - шаблоны по умолчанию для Language (ru или en)
+	Language         string `json:"language,omitempty"`
+	FiringTemplate   string `json:"firing_template,omitempty"`
+	ResolvedTemplate string `json:"resolved_template,omitempty"`
+	GroupTemplate    string `json:"group_template,omitempty"`
+	DigestTemplate   string `json:"digest_template,omitempty"`
+	// GroupWindowSeconds оповещения, поставленные в очередь в течение окна,
+	// отправляются одним сообщением со списком алертов; 0 - по одному
+	GroupWindowSeconds int `json:"group_window_seconds,omitempty" binding:"min=0"`
+	// DigestIntervalMinutes канал получает не отдельные оповещения, а сводку
+	// открытых и разрешенных алертов раз в указанное число минут; 0 - выключено
+	DigestIntervalMinutes int `json:"digest_interval_minutes,omitempty" binding:"min=0"`
+}
+
+// Value сериализует настройки для записи в БД
+func (c ChannelConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan читает настройки из БД
+func (c *ChannelConfig) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = ChannelConfig{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return errors.New("неподдерживаемый тип config")
+}
+
+// ChannelRequest запрос на создание или изменение канала
+type ChannelRequest struct {
+	Name   string        `json:"name" binding:"required"`
+	Type   string        `json:"type" binding:"required,oneof=webhook email"`
+	Config ChannelConfig `json:"config"`
+}
+
+// Secret секрет из хранилища; значение через API не возвращается
+type Secret struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SecretRequest запрос на создание или замену секрета
+type SecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// ChannelType типы каналов оповещений
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// Responder дежурный и канал, по которому до него можно достучаться
+type Responder struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	ChannelID int       `json:"channel_id" db:"channel_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ResponderRequest запрос на создание или изменение дежурного
+type ResponderRequest struct {
+	Name      string `json:"name" binding:"required"`
+	ChannelID int    `json:"channel_id" binding:"required"`
+}
+
+// Schedule график дежурств: участники сменяют друг друга по кругу
+// каждые сутки или неделю, начиная с StartsAt
+type Schedule struct {
+	ID           int                `json:"id" db:"id"`
+	Name         string             `json:"name" db:"name"`
+	Rotation     string             `json:"rotation" db:"rotation"`
+	StartsAt     time.Time          `json:"starts_at" db:"starts_at"`
+	Participants []int              `json:"participants" db:"participants"` // ID дежурных в порядке смен
+	CreatedAt    time.Time          `json:"created_at" db:"created_at"`
+	Overrides    []ScheduleOverride `json:"overrides,omitempty"`
+}
+
+// ScheduleRequest запрос на создание или изменение графика
+type ScheduleRequest struct {
+	Name         string    `json:"name" binding:"required"`
+	Rotation     string    `json:"rotation" binding:"required,oneof=daily weekly"`
+	StartsAt     time.Time `json:"starts_at" binding:"required"`
+	Participants []int     `json:"participants" binding:"required,min=1"`
+}
+
+// ScheduleOverride подмена: на время [StartsAt, EndsAt) дежурит ResponderID
+type ScheduleOverride struct {
+	ID          int       `json:"id" db:"id"`
+	ScheduleID  int       `json:"schedule_id" db:"schedule_id"`
+	ResponderID int       `json:"responder_id" db:"responder_id"`
+	StartsAt    time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt      time.Time `json:"ends_at" db:"ends_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// OverrideRequest запрос на создание подмены
+type OverrideRequest struct {
+	ResponderID int       `json:"responder_id" binding:"required"`
+	StartsAt    time.Time `json:"starts_at" binding:"required"`
+	EndsAt      time.Time `json:"ends_at" binding:"required"`
+}
+
+// OnCall кто дежурит по графику в указанный момент
+type OnCall struct {
+	ScheduleID   int        `json:"schedule_id"`
+	ScheduleName string     `json:"schedule_name"`
+	Responder    *Responder `json:"responder"`
+	Override     bool       `json:"override"`
+	Until        time.Time  `json:"until"` // до передачи смены или конца подмены
+}
+
+// Rotation периоды смены дежурных
+const (
+	RotationDaily  = "daily"
+	RotationWeekly = "weekly"
+)
+
+// EscalationPolicy уровни оповещения: если алерт не подтвержден за
+// TimeoutMinutes уровня, оповещается следующий уровень
+type EscalationPolicy struct {
+	ID        int              `json:"id" db:"id"`
+	Name      string           `json:"name" db:"name"`
+	Levels    EscalationLevels `json:"levels" db:"levels"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// EscalationLevel один уровень политики; указывается ровно одна цель:
+// график (оповещается текущий дежурный), дежурный или канал
+type EscalationLevel struct {
+	TimeoutMinutes int  `json:"timeout_minutes"`
+	ScheduleID     *int `json:"schedule_id,omitempty"`
+	ResponderID    *int `json:"responder_id,omitempty"`
+	ChannelID      *int `json:"channel_id,omitempty"`
+}
+
+// EscalationLevels уровни политики, хранятся в escalation_policies.levels (JSONB)
+type EscalationLevels []EscalationLevel
+
+// Value сериализует уровни для записи в БД
+func (l EscalationLevels) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+// Scan читает уровни из БД
+func (l *EscalationLevels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return errors.New("неподдерживаемый тип levels")
+}
+
+// EscalationPolicyRequest запрос на создание или изменение политики
+type EscalationPolicyRequest struct {
+	Name   string           `json:"name" binding:"required"`
+	Levels EscalationLevels `json:"levels" binding:"required,min=1"`
+}
+
+// AcknowledgeRequest запрос на подтверждение алерта
+type AcknowledgeRequest struct {
+	By string `json:"by" binding:"required"`
+}
+
+// Notification оповещение об алерте в очереди доставки
+type Notification struct {
+	ID            int               `json:"id" db:"id"`
+	AlertID       int               `json:"alert_id" db:"alert_id"`
+	ChannelID     int               `json:"channel_id" db:"channel_id"`
+	ChannelName   string            `json:"channel_name"`
+	Recipient     string            `json:"recipient" db:"recipient"`
+	Event         string            `json:"event" db:"event"`
+	Status        string            `json:"status" db:"status"` // pending, sent или failed
+	Attempts      int               `json:"attempts" db:"attempts"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastError     string            `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	SentAt        *time.Time        `json:"sent_at,omitempty" db:"sent_at"`
+	Log           []DeliveryAttempt `json:"log"`
+}
+
+// DeliveryAttempt одна попытка доставки оповещения
+type DeliveryAttempt struct {
+	Attempt     int       `json:"attempt" db:"attempt"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+	DurationMs  int64     `json:"duration_ms" db:"duration_ms"`
+	StatusCode  *int      `json:"status_code,omitempty" db:"status_code"`
+	Error       string    `json:"error,omitempty" db:"error"`
+	Success     bool      `json:"success" db:"success"`
+}
+
+// Labels произвольные метки, хранятся в JSONB
+type Labels map[string]string
+
+// Value сериализует метки для записи в БД
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]string(l))
+}
+
+// Scan читает метки из БД
+func (l *Labels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return errors.New("неподдерживаемый тип labels")
+}
+
+// AlertRule пользовательское правило алерта. Метрика считается по проверкам
+// каждого подходящего сервиса за последние WindowSeconds; алерт открывается,
+// если условие выполняется не меньше ForSeconds подряд
+type AlertRule struct {
+	ID        int     `json:"id" db:"id"`
+	Name      string  `json:"name" db:"name"`
+	ServiceID *int    `json:"service_id,omitempty" db:"service_id"` // сервис или группа; ни то ни другое - все сервисы
+	Group     string  `json:"group,omitempty" db:"group_name"`
+	Metric    string  `json:"metric" db:"metric"`
+	Operator  string  `json:"operator" db:"operator"`
+	Threshold float64 `json:"threshold" db:"threshold"`
+	// WindowSeconds окно расчета метрики; для last_check_age не используется
+	WindowSeconds int       `json:"window_seconds" db:"window_seconds"`
+	ForSeconds    int       `json:"for_seconds" db:"for_seconds"`
+	Severity      string    `json:"severity" db:"severity"`
+	Labels        Labels    `json:"labels" db:"labels"`
+	Channels      []int     `json:"channels" db:"channels"`
+	Enabled       bool      `json:"enabled" db:"enabled"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// AlertRuleRequest запрос на создание, изменение или предпросмотр правила
+type AlertRuleRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	ServiceID     *int    `json:"service_id"`
+	Group         string  `json:"group"`
+	Metric        string  `json:"metric" binding:"required,oneof=p95_latency avg_latency error_rate uptime last_check_age"`
+	Operator      string  `json:"operator" binding:"required"`
+	Threshold     float64 `json:"threshold"`
+	WindowSeconds int     `json:"window_seconds"`
+	ForSeconds    int     `json:"for_seconds"`
+	Severity      string  `json:"severity" binding:"required,oneof=info warning error critical"`
+	Labels        Labels  `json:"labels"`
+	Channels      []int   `json:"channels"`
+	Enabled       *bool   `json:"enabled"`
+}
+
+// RuleMetric метрики правил алертов
+const (
+	MetricP95Latency   = "p95_latency"    // мс
+	MetricAvgLatency   = "avg_latency"    // мс
+	MetricErrorRate    = "error_rate"     // процент неуспешных проверок
+	MetricUptime       = "uptime"         // процент, взвешенный по времени
+	MetricLastCheckAge = "last_check_age" // секунды с последней проверки
+)
+
+// RulePreview результат правила на исторических данных для одного сервиса
+type RulePreview struct {
+	ServiceID   int                `json:"service_id"`
+	ServiceName string             `json:"service_name"`
+	Points      []RulePreviewPoint `json:"points"`
+	Firing      []FiringPeriod     `json:"firing"`
+}
+
+// RulePreviewPoint значение метрики в момент времени; Value nil - нет данных
+type RulePreviewPoint struct {
+	Time    time.Time `json:"time"`
+	Value   *float64  `json:"value"`
+	Matches bool      `json:"matches"`
+}
+
+// FiringPeriod период, когда алерт правила был бы открыт; End nil - открыт до сих пор
+type FiringPeriod struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Route узел дерева маршрутизации алертов. Алерт спускается в первый
+// подходящий дочерний маршрут; при Continue проверяются и следующие.
+// Если ни один дочерний маршрут не подошел, алерт получает сам узел
+type Route struct {
+	Matchers []Matcher `json:"matchers,omitempty"` // все должны совпасть; у корня игнорируются
+	Channels []int     `json:"channels,omitempty"` // пустой - каналы родительского маршрута
+	Continue bool      `json:"continue,omitempty"`
+	// GroupWaitSeconds сколько ждать после первого алерта маршрута, собирая
+	// следующие в одно сообщение; алерт, разрешившийся раньше, не оповещается
+	GroupWaitSeconds int `json:"group_wait_seconds,omitempty"`
+	// RepeatIntervalSeconds период повтора оповещения об активном алерте, 0 - без повторов
+	RepeatIntervalSeconds int     `json:"repeat_interval_seconds,omitempty"`
+	Routes                []Route `json:"routes,omitempty"`
+}
+
+// Matcher условие на метку алерта: =, !=, =~ или !~ (регулярное выражение целиком)
+type Matcher struct {
+	Label string `json:"label"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// Value сериализует дерево для записи в БД
+func (r Route) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan читает дерево из БД
+func (r *Route) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Route{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return errors.New("неподдерживаемый тип tree")
+}
+
+// MatchedRoute маршрут, выбранный для алерта; Path - индексы от корня, например "0.1"
+type MatchedRoute struct {
+	Path  string `json:"path"`
+	Route Route  `json:"route"`
+}
+
+// RouteTestRequest запрос проверки маршрутизации: метки или существующий алерт
+type RouteTestRequest struct {
+	AlertID *int   `json:"alert_id"`
+	Labels  Labels `json:"labels"`
+}
+
+// RouteTestResult куда попал бы алерт
+type RouteTestResult struct {
+	Labels   Labels         `json:"labels"`
+	Routes   []MatchedRoute `json:"routes"`
+	Channels []int          `json:"channels"`
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/probe"
+)
+
+// p95Window количество последних проверок, по которым считается p95
+const p95Window = 20
+
+// applyDegradation переводит успешную проверку в статус degraded,
+// если время ответа превышает пороги сервиса
+func (s *Service) applyDegradation(service models.Service, result *probe.Result) {
+	if result.Status != models.StatusHealthy {
+		return
+	}
+
+	if service.DegradedThreshold > 0 && result.ResponseTime > service.DegradedThreshold {
+		result.Status = models.StatusDegraded
+		result.ErrorMessage = fmt.Sprintf("время ответа %d мс превышает порог %d мс",
+			result.ResponseTime, service.DegradedThreshold)
+		return
+	}
+
+	if service.DegradedP95Threshold > 0 {
+		times, err := s.recentResponseTimes(service.ID, p95Window-1)
+		if err != nil {
+			s.logger.Error("Ошибка получения истории времени ответа:", err)
+			return
+		}
+
+		p95 := percentile(append(times, result.ResponseTime), 0.95)
+		if p95 > service.DegradedP95Threshold {
+			result.Status = models.StatusDegraded
+			result.ErrorMessage = fmt.Sprintf("p95 времени ответа %d мс превышает порог %d мс",
+				p95, service.DegradedP95Threshold)
+		}
+	}
+}
+
+// recentResponseTimes возвращает время ответа последних успешных проверок,
+// включая еще не записанные в БД
+func (s *Service) recentResponseTimes(serviceID, limit int) ([]int, error) {
+	pending := s.writer.pending(serviceID)
+
+	query := `
+		SELECT response_time, location, checked_at
+		FROM health_checks
+		WHERE service_id = $1 AND status <> 'unhealthy'
+		ORDER BY checked_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(query, serviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []models.HealthCheck
+	for rows.Next() {
+		var check models.HealthCheck
+		if err := rows.Scan(&check.ResponseTime, &check.Location, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		stored = append(stored, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	checks := mergePending(stored, pending, func(check models.HealthCheck) bool {
+		return check.Status != models.StatusUnhealthy
+	})
+
+	var times []int
+	for i := 0; i < len(checks) && i < limit; i++ {
+		times = append(times, checks[i].ResponseTime)
+	}
+	return times, nil
+}
+
+// percentile вычисляет перцентиль методом ближайшего ранга
+func percentile(values []int, p float64) int {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
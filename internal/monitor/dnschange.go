@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/models"
+	"service-monitor/internal/probe"
+)
+
+// ErrNoBaseline у сервиса еще нет эталонных ответов DNS
+var ErrNoBaseline = errors.New("эталонных ответов DNS нет")
+
+// checkDNSChange сравнивает ответ DNS проверки с эталоном локации. Первый
+// успешный ответ становится эталоном; отличие открывает алерт dns_change,
+// который разрешается, когда ответы всех локаций снова совпадают с эталоном
+func (s *Service) checkDNSChange(service models.Service, location string, result *probe.Result) error {
+	cfg := service.ProbeConfig
+	if cfg.Type != models.ProbeDNS || cfg.DNS == nil || !cfg.DNS.AlertOnChange || result.Answers == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO dns_baselines (service_id, location, answers, last_answers)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (service_id, location) DO UPDATE
+		SET last_answers = EXCLUDED.last_answers, updated_at = CURRENT_TIMESTAMP,
+		    changed_at = CASE
+		        WHEN dns_baselines.answers = EXCLUDED.last_answers THEN NULL
+		        ELSE COALESCE(dns_baselines.changed_at, CURRENT_TIMESTAMP)
+		    END
+		RETURNING answers
+	`
+
+	var baseline []string
+	err := s.db.QueryRow(query, service.ID, location, pq.Array(result.Answers)).Scan(pq.Array(&baseline))
+	if err != nil {
+		return err
+	}
+
+	recordType := cfg.DNS.RecordType
+	if recordType == "" {
+		recordType = models.RecordA
+	}
+
+	fingerprint := alerting.ServiceFingerprint(service.ID, models.AlertKindDNSChange)
+	if strings.Join(baseline, "\n") != strings.Join(result.Answers, "\n") {
+		_, err := s.alerts.Fire(models.Alert{
+			ServiceID: service.ID,
+			Message: fmt.Sprintf("Ответ DNS %s (%s) из %s изменился: было %s, стало %s", service.URL,
+				strings.ToUpper(recordType), location, strings.Join(baseline, ", "), strings.Join(result.Answers, ", ")),
+			Severity:    models.SeverityWarning,
+			Kind:        models.AlertKindDNSChange,
+			Fingerprint: fingerprint,
+		})
+		return err
+	}
+
+	var changed bool
+	err = s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM dns_baselines WHERE service_id = $1 AND changed_at IS NOT NULL)`,
+		service.ID).Scan(&changed)
+	if err != nil || changed {
+		return err
+	}
+
+	_, err = s.alerts.Resolve(fingerprint)
+	return err
+}
+
+// DNSBaselines эталонные и последние ответы DNS проверки сервиса по локациям
+func (s *Service) DNSBaselines(serviceID int) ([]models.DNSBaseline, error) {
+	query := `
+		SELECT service_id, location, answers, last_answers, changed_at, updated_at
+		FROM dns_baselines
+		WHERE service_id = $1
+		ORDER BY location
+	`
+
+	rows, err := s.db.Query(query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines := []models.DNSBaseline{}
+	for rows.Next() {
+		var b models.DNSBaseline
+		if err := rows.Scan(&b.ServiceID, &b.Location, pq.Array(&b.Answers), pq.Array(&b.LastAnswers),
+			&b.ChangedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, b)
+	}
+	return baselines, rows.Err()
+}
+
+// AcceptDNSBaseline принимает последние ответы как новый эталон и
+// разрешает алерт об изменении
+func (s *Service) AcceptDNSBaseline(serviceID int) error {
+	result, err := s.db.Exec(`
+		UPDATE dns_baselines SET answers = last_answers, changed_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE service_id = $1
+	`, serviceID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNoBaseline
+	}
+
+	_, err = s.alerts.Resolve(alerting.ServiceFingerprint(serviceID, models.AlertKindDNSChange))
+	return err
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"fmt"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/models"
+)
+
+// updateFlapping пересчитывает долю смен состояния по последним проверкам
+// из локации, включая текущую со статусом status, и открывает или закрывает
+// алерт flapping. Возвращает true, пока сервис считается нестабильным
+func (s *Service) updateFlapping(service models.Service, location, status string) bool {
+	window := s.config.FlapWindow
+	if window < 2 {
+		return false
+	}
+
+	// Текущая проверка уже в буфере записи и попадает в историю
+	statuses, err := s.recentStatuses(service.ID, location, window)
+	if err != nil {
+		s.logger.Error("Ошибка получения истории статусов:", err)
+		return false
+	}
+
+	flapping, err := s.alerts.IsActive(alerting.ServiceFingerprint(service.ID, models.AlertKindFlapping))
+	if err != nil {
+		s.logger.Error("Ошибка проверки алертов:", err)
+		return false
+	}
+
+	rate := changeRate(statuses)
+
+	switch {
+	case !flapping && len(statuses) >= window && rate >= s.config.FlapHighThreshold:
+		// Вместо череды алертов down/degraded держим один алерт flapping
+		s.resolveAlerts(service.ID, models.AlertKindDown, models.AlertKindDegraded)
+		s.checkAndCreateAlert(service, models.AlertKindFlapping, models.SeverityWarning,
+			fmt.Sprintf("Сервис %s нестабилен: %.0f%% смен состояния за последние %d проверок",
+				service.Name, rate*100, len(statuses)))
+		return true
+
+	case flapping && rate < s.config.FlapLowThreshold:
+		s.resolveAlerts(service.ID, models.AlertKindFlapping)
+		s.logger.Info("Сервис стабилизировался:", service.Name)
+		return false
+	}
+
+	return flapping
+}
+
+// recentStatuses возвращает статусы последних проверок из локации, от новых
+// к старым, включая еще не записанные в БД. Проверки разных локаций не
+// смешиваются, иначе расхождение между ними выглядело бы как нестабильность
+func (s *Service) recentStatuses(serviceID int, location string, limit int) ([]string, error) {
+	pending := s.writer.pending(serviceID)
+
+	query := `
+		SELECT status, checked_at
+		FROM health_checks
+		WHERE service_id = $1 AND location = $2
+		ORDER BY checked_at DESC
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(query, serviceID, location, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []models.HealthCheck
+	for rows.Next() {
+		check := models.HealthCheck{Location: location}
+		if err := rows.Scan(&check.Status, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		stored = append(stored, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	checks := mergePending(stored, pending, func(check models.HealthCheck) bool {
+		return check.Location == location
+	})
+
+	var statuses []string
+	for i := 0; i < len(checks) && i < limit; i++ {
+		statuses = append(statuses, checks[i].Status)
+	}
+	return statuses, nil
+}
+
+// changeRate доля соседних проверок с разным статусом
+func changeRate(statuses []string) float64 {
+	if len(statuses) < 2 {
+		return 0
+	}
+
+	changes := 0
+	for i := 1; i < len(statuses); i++ {
+		if statuses[i] != statuses[i-1] {
+			changes++
+		}
+	}
+
+	return float64(changes) / float64(len(statuses)-1)
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// mergePending дополняет результаты из БД еще не записанными результатами,
+// прошедшими keep, и упорядочивает их от новых к старым. История в БД отстает
+// на RESULT_FLUSH_INTERVAL, а решения о деградации, нестабильности и кворуме
+// должны учитывать все результаты. Результат, записанный между чтением
+// буфера и запросом к БД, попадает в оба списка и учитывается один раз
+func mergePending(stored, pending []models.HealthCheck, keep func(models.HealthCheck) bool) []models.HealthCheck {
+	type key struct {
+		location string
+		at       int64
+	}
+	// PostgreSQL хранит время с точностью до микросекунд
+	keyOf := func(check models.HealthCheck) key {
+		return key{check.Location, check.CheckedAt.Round(time.Microsecond).UnixMicro()}
+	}
+
+	seen := make(map[key]bool, len(stored))
+	merged := append([]models.HealthCheck(nil), stored...)
+	for _, check := range stored {
+		seen[keyOf(check)] = true
+	}
+	for _, check := range pending {
+		if keep(check) && !seen[keyOf(check)] {
+			merged = append(merged, check)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CheckedAt.After(merged[j].CheckedAt)
+	})
+	return merged
+}
@@ -3,131 +3,238 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"sync"
 	"time"
 
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/config"
 	"service-monitor/internal/database"
 	"service-monitor/internal/logger"
 	"service-monitor/internal/models"
+	"service-monitor/internal/probe"
+	"service-monitor/internal/secrets"
 )
 
 type Service struct {
-	db     *database.DB
-	logger *logger.Logger
-	ticker *time.Ticker
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	config  *config.Config
+	db      *database.DB
+	alerts  *alerting.Manager
+	secrets *secrets.Store
+	logger  *logger.Logger
+	writer  *writer
+	ticker  *time.Ticker
+
+	// abort прерывает проверки, запущенные текущим Run; pool - пул текущего Run
+	mu    sync.Mutex
+	abort context.CancelFunc
+	pool  *pool
 }
 
-func NewService(db *database.DB, logger *logger.Logger) *Service {
-	ctx, cancel := context.WithCancel(context.Background())
-	
+func NewService(cfg *config.Config, db *database.DB, alerts *alerting.Manager, secrets *secrets.Store,
+	logger *logger.Logger) *Service {
 	return &Service{
-		db:     db,
-		logger: logger,
-		ctx:    ctx,
-		cancel: cancel,
+		config:  cfg,
+		db:      db,
+		alerts:  alerts,
+		secrets: secrets,
+		logger:  logger,
+		writer: newWriter(db, logger, cfg.ResultBatchSize, cfg.ResultBufferSize,
+			time.Duration(cfg.ResultFlushInterval)*time.Second),
 	}
 }
 
-func (s *Service) Start() {
+// Run запускает плановые проверки и блокируется до отмены ctx. Проверки
+// выполняет пул из CHECK_CONCURRENCY обработчиков, не больше
+// CHECK_HOST_CONCURRENCY на хост. После отмены проверки из очереди
+// отменяются, а Run дожидается завершения уже начатых. Прервать их можно через Abort
+func (s *Service) Run(ctx context.Context) {
 	s.logger.Info("Запуск мониторинга сервисов")
+
+	probeCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+
+	pool := newPool(s.config.CheckConcurrency, s.config.CheckHostConcurrency, s.checkService)
+	pool.start(probeCtx)
+
+	s.mu.Lock()
+	s.abort = abort
+	s.pool = pool
+	s.mu.Unlock()
 	
 	// Запускаем проверку каждые 30 секунд
 	s.ticker = time.NewTicker(30 * time.Second)
 	defer s.ticker.Stop()
 
 	// Первая проверка сразу
-	s.checkAllServices()
+	s.checkAllServices(pool)
 
 	for {
 		select {
 		case <-s.ticker.C:
-			s.checkAllServices()
-		case <-s.ctx.Done():
-			s.logger.Info("Остановка мониторинга")
+			s.checkAllServices(pool)
+		case <-ctx.Done():
+			s.logger.Info("Остановка мониторинга, ожидание незавершенных проверок")
+			if dropped := pool.stop(); dropped > 0 {
+				s.logger.Infof("Отменено проверок из очереди: %d", dropped)
+			}
+			s.logger.Info("Мониторинг остановлен")
 			return
 		}
 	}
 }
 
-func (s *Service) Stop() {
-	s.cancel()
-	s.wg.Wait()
+// Abort прерывает незавершенные проверки; их результаты не сохраняются
+func (s *Service) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.abort != nil {
+		s.abort()
+	}
 }
 
-func (s *Service) checkAllServices() {
+func (s *Service) checkAllServices(pool *pool) {
 	services, err := s.getServices()
 	if err != nil {
 		s.logger.Error("Ошибка получения сервисов:", err)
 		return
 	}
 
-	for _, service := range services {
-		s.wg.Add(1)
-		go func(service models.Service) {
-			defer s.wg.Done()
-			s.checkService(service)
-		}(service)
+	if skipped := pool.submit(services); skipped > 0 {
+		stats := pool.stats()
+		s.logger.Errorf("Проверки отстают от расписания: %d сервисов не проверены с прошлого цикла "+
+			"(в очереди %d, из них ждут лимита хоста %d, выполняется %d, ожидание в очереди до %d мс)",
+			skipped, stats.Queued, stats.HostLimited, stats.Running, stats.MaxQueueWaitMs)
 	}
 }
 
-func (s *Service) checkService(service models.Service) {
-	start := time.Now()
-	
-	client := &http.Client{
-		Timeout: time.Duration(service.Timeout) * time.Second,
+// PoolStats состояние пула проверок; без лидерства пул не работает
+func (s *Service) PoolStats() PoolStats {
+	s.mu.Lock()
+	pool := s.pool
+	s.mu.Unlock()
+
+	if pool == nil {
+		return PoolStats{Workers: s.config.CheckConcurrency, HostConcurrency: s.config.CheckHostConcurrency}
 	}
+	return pool.stats()
+}
 
-	resp, err := client.Get(service.URL)
-	responseTime := int(time.Since(start).Milliseconds())
+// RunWriter записывает результаты проверок в БД пачками до отмены ctx,
+// затем записывает остаток. Результаты принимает любой экземпляр, не только
+// лидер, поэтому ctx стоит отменять после остановки мониторинга и API
+func (s *Service) RunWriter(ctx context.Context) {
+	s.writer.run(ctx)
+}
 
-	var status string
-	var errorMessage string
+// WriterStats состояние буфера результатов проверок
+func (s *Service) WriterStats() WriterStats {
+	return s.writer.stats()
+}
 
+func (s *Service) checkService(ctx context.Context, service models.Service) {
+	if _, err := s.CheckNow(ctx, service); err != nil {
+		s.logger.Error("Ошибка проверки сервиса:", err)
+	}
+}
+
+// CheckNow выполняет проверку сервиса немедленно, сохраняет результат
+// и обновляет алерты так же, как плановая проверка
+func (s *Service) CheckNow(ctx context.Context, service models.Service) (probe.Result, error) {
+	result := s.probe(ctx, service)
+
+	// Прерванная проверка ничего не говорит о сервисе, не сохраняем ее
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("проверка сервиса %s прервана: %w", service.Name, ctx.Err())
+	}
+
+	err := s.Record(service, s.config.Location, &result)
+	return result, err
+}
+
+// probe подставляет секреты и выполняет проверку; ненайденный секрет
+// делает проверку неуспешной. Значения секретов в сообщениях результата
+// заменяются ссылками: сообщения сохраняются в истории и попадают в алерты
+func (s *Service) probe(ctx context.Context, service models.Service) probe.Result {
+	resolved, concealer, err := s.secrets.ResolveService(service)
 	if err != nil {
-		status = models.StatusUnhealthy
-		errorMessage = err.Error()
-		s.logger.Error("Сервис", service.Name, "недоступен:", err)
-	} else {
-		defer resp.Body.Close()
-		
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			status = models.StatusHealthy
-		} else {
-			status = models.StatusUnhealthy
-			errorMessage = fmt.Sprintf("HTTP %d", resp.StatusCode)
-		}
+		return probe.Result{Status: models.StatusUnhealthy, ErrorMessage: err.Error(), CheckedAt: time.Now()}
+	}
+	result := probe.Run(ctx, resolved)
+	result.RewriteMessages(concealer.Conceal)
+	return result
+}
+
+// Record сохраняет результат проверки из указанной локации, пересчитывает
+// статус сервиса по кворуму локаций и обновляет алерты
+func (s *Service) Record(service models.Service, location string, result *probe.Result) error {
+	s.applyDegradation(service, result)
+
+	if result.Status == models.StatusUnhealthy {
+		s.logger.Errorf("Сервис %s недоступен из %s: %s", service.Name, location, result.ErrorMessage)
 	}
 
-	// Сохраняем результат проверки
+	status, message, err := s.quorumStatus(service.ID, locationResult{
+		Location:     location,
+		Status:       result.Status,
+		ErrorMessage: result.ErrorMessage,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Сохраняем результат проверки вместе с итоговым статусом сервиса:
+	// по нему считается uptime. Запись асинхронная, в истории результат
+	// появится через RESULT_FLUSH_INTERVAL
 	check := models.HealthCheck{
-		ServiceID:    service.ID,
-		Status:       status,
-		ResponseTime: responseTime,
-		ErrorMessage: errorMessage,
-		CheckedAt:    time.Now(),
+		ServiceID:     service.ID,
+		Status:        result.Status,
+		ResponseTime:  result.ResponseTime,
+		ErrorMessage:  result.ErrorMessage,
+		CheckedAt:     result.CheckedAt,
+		Location:      location,
+		Timings:       result.Timings,
+		ServiceStatus: status,
+	}
+	if result.Ping != nil {
+		check.PacketLoss = &result.Ping.Loss
 	}
 
-	if err := s.saveHealthCheck(check); err != nil {
-		s.logger.Error("Ошибка сохранения проверки:", err)
-		return
+	s.writer.add(check)
+
+	if err := s.setServiceStatus(service.ID, status); err != nil {
+		return err
+	}
+	if err := s.checkDNSChange(service, location, result); err != nil {
+		s.logger.Error("Ошибка сравнения ответа DNS с эталоном:", err)
+	}
+
+	// Пока сервис нестабилен, алерты down/degraded не создаются и не разрешаются
+	if s.updateFlapping(service, location, result.Status) {
+		return nil
 	}
 
 	// Проверяем необходимость создания алерта
-	if status == models.StatusUnhealthy {
-		s.checkAndCreateAlert(service, errorMessage)
-	} else {
+	switch status {
+	case models.StatusUnhealthy:
+		s.resolveAlerts(service.ID, models.AlertKindDegraded)
+		s.checkAndCreateAlert(service, models.AlertKindDown, models.SeverityError,
+			fmt.Sprintf("Сервис %s недоступен: %s", service.Name, message))
+	case models.StatusDegraded:
+		s.resolveAlerts(service.ID, models.AlertKindDown)
+		s.checkAndCreateAlert(service, models.AlertKindDegraded, models.SeverityWarning,
+			fmt.Sprintf("Сервис %s работает медленно: %s", service.Name, message))
+	default:
 		// Если сервис восстановился, разрешаем алерты
-		s.resolveAlerts(service.ID)
+		s.resolveAlerts(service.ID, models.AlertKindDown, models.AlertKindDegraded)
 	}
+
+	return nil
 }
 
 func (s *Service) getServices() ([]models.Service, error) {
-	query := `SELECT id, name, url, check_interval, timeout, created_at, updated_at FROM services`
-	
+	query := `SELECT ` + database.ServiceColumns + ` FROM services`
+
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -137,16 +244,7 @@ func (s *Service) getServices() ([]models.Service, error) {
 	var services []models.Service
 	for rows.Next() {
 		var service models.Service
-		err := rows.Scan(
-			&service.ID,
-			&service.Name,
-			&service.URL,
-			&service.CheckInterval,
-			&service.Timeout,
-			&service.CreatedAt,
-			&service.UpdatedAt,
-		)
-		if err != nil {
+		if err := database.ScanService(rows, &service); err != nil {
 			return nil, err
 		}
 		services = append(services, service)
@@ -155,59 +253,27 @@ func (s *Service) getServices() ([]models.Service, error) {
 	return services, nil
 }
 
-func (s *Service) saveHealthCheck(check models.HealthCheck) error {
-	query := `
-		INSERT INTO health_checks (service_id, status, response_time, error_message, checked_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`
-	
-	_, err := s.db.Exec(query, check.ServiceID, check.Status, check.ResponseTime, check.ErrorMessage, check.CheckedAt)
-	return err
-}
-
-func (s *Service) checkAndCreateAlert(service models.Service, errorMessage string) {
-	// Проверяем, есть ли уже активный алерт для этого сервиса
-	query := `SELECT COUNT(*) FROM alerts WHERE service_id = $1 AND is_resolved = false`
-	
-	var count int
-	err := s.db.QueryRow(query, service.ID).Scan(&count)
+func (s *Service) checkAndCreateAlert(service models.Service, kind, severity, message string) {
+	_, err := s.alerts.Fire(models.Alert{
+		ServiceID:   service.ID,
+		Message:     message,
+		Severity:    severity,
+		Kind:        kind,
+		Fingerprint: alerting.ServiceFingerprint(service.ID, kind),
+	})
 	if err != nil {
-		s.logger.Error("Ошибка проверки алертов:", err)
-		return
+		s.logger.Error("Ошибка создания алерта:", err)
 	}
+}
 
-	// Если алерта нет, создаем новый
-	if count == 0 {
-		alert := models.Alert{
-			ServiceID:  service.ID,
-			Message:    fmt.Sprintf("Сервис %s недоступен: %s", service.Name, errorMessage),
-			Severity:   models.SeverityError,
-			IsResolved: false,
-			CreatedAt:  time.Now(),
-		}
-
-		insertQuery := `
-			INSERT INTO alerts (service_id, message, severity, is_resolved, created_at)
-			VALUES ($1, $2, $3, $4, $5)
-		`
-		
-		_, err := s.db.Exec(insertQuery, alert.ServiceID, alert.Message, alert.Severity, alert.IsResolved, alert.CreatedAt)
-		if err != nil {
-			s.logger.Error("Ошибка создания алерта:", err)
-		} else {
-			s.logger.Info("Создан алерт для сервиса:", service.Name)
-		}
+// resolveAlerts разрешает активные алерты сервиса указанных типов
+func (s *Service) resolveAlerts(serviceID int, kinds ...string) error {
+	fingerprints := make([]string, len(kinds))
+	for i, kind := range kinds {
+		fingerprints[i] = alerting.ServiceFingerprint(serviceID, kind)
 	}
-}
 
-func (s *Service) resolveAlerts(serviceID int) error {
-	query := `
-		UPDATE alerts 
-		SET is_resolved = true, resolved_at = CURRENT_TIMESTAMP 
-		WHERE service_id = $1 AND is_resolved = false
-	`
-	
-	_, err := s.db.Exec(query, serviceID)
+	_, err := s.alerts.Resolve(fingerprints...)
 	if err != nil {
 		s.logger.Error("Ошибка разрешения алертов:", err)
 	}
@@ -0,0 +1,236 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+)
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, 0, percentile(nil, 0.95))
+	assert.Equal(t, 42, percentile([]int{42}, 0.95))
+
+	values := make([]int, 0, 20)
+	for i := 20; i >= 1; i-- {
+		values = append(values, i*100)
+	}
+	assert.Equal(t, 1900, percentile(values, 0.95))
+	assert.Equal(t, 1000, percentile(values, 0.5))
+}
+
+func TestChangeRate(t *testing.T) {
+	assert.Equal(t, 0.0, changeRate(nil))
+	assert.Equal(t, 0.0, changeRate([]string{"healthy"}))
+	assert.Equal(t, 0.0, changeRate([]string{"healthy", "healthy", "healthy"}))
+	assert.Equal(t, 1.0, changeRate([]string{"healthy", "unhealthy", "healthy", "unhealthy"}))
+	assert.InDelta(t, 0.333, changeRate([]string{"healthy", "healthy", "unhealthy", "unhealthy"}), 0.001)
+}
+
+func TestDecideStatus(t *testing.T) {
+	status, message := decideStatus(nil, 0)
+	assert.Equal(t, "unknown", status)
+	assert.Empty(t, message)
+
+	// Одна локация: статус и причина без изменений
+	status, message = decideStatus([]locationResult{
+		{Location: "local", Status: "unhealthy", ErrorMessage: "HTTP 500"},
+	}, 0)
+	assert.Equal(t, "unhealthy", status)
+	assert.Equal(t, "HTTP 500", message)
+
+	results := []locationResult{
+		{Location: "dc1", Status: "unhealthy", ErrorMessage: "timeout"},
+		{Location: "dc2", Status: "healthy"},
+		{Location: "dc3", Status: "healthy"},
+	}
+
+	// Сбой только из одной локации из трех - большинства нет
+	status, _ = decideStatus(results, 0)
+	assert.Equal(t, "healthy", status)
+
+	// Явный кворум в одну локацию
+	status, _ = decideStatus(results, 1)
+	assert.Equal(t, "unhealthy", status)
+
+	results[1] = locationResult{Location: "dc2", Status: "unhealthy", ErrorMessage: "HTTP 502"}
+	status, message = decideStatus(results, 0)
+	assert.Equal(t, "unhealthy", status)
+	assert.Equal(t, "из 2 из 3 локаций (dc1: timeout; dc2: HTTP 502)", message)
+
+	// Сбой и деградация вместе дают деградацию
+	results[1] = locationResult{Location: "dc2", Status: "degraded", ErrorMessage: "slow"}
+	status, _ = decideStatus(results, 0)
+	assert.Equal(t, "degraded", status)
+}
+
+func TestPool(t *testing.T) {
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	release := make(chan struct{})
+
+	p := newPool(4, 1, func(ctx context.Context, service models.Service) {
+		host := hostKey(service.URL)
+		mu.Lock()
+		running[host]++
+		if running[host] > maxRunning[host] {
+			maxRunning[host] = running[host]
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		running[host]--
+		mu.Unlock()
+	})
+	p.start(context.Background())
+
+	services := []models.Service{
+		{ID: 1, URL: "https://api.example.com/a"},
+		{ID: 2, URL: "https://api.example.com/b"},
+		{ID: 3, URL: "https://api.example.com/c"},
+		{ID: 4, URL: "db.example.com:5432"},
+	}
+	assert.Equal(t, 0, p.submit(services))
+
+	assert.Eventually(t, func() bool { return p.stats().Running == 2 }, time.Second, 5*time.Millisecond)
+	stats := p.stats()
+	assert.Equal(t, 2, stats.Queued)
+	assert.Equal(t, 2, stats.HostLimited)
+
+	// Проверки прошлого цикла не завершились: сервисы не ставятся повторно
+	assert.Equal(t, 4, p.submit(services))
+	assert.Equal(t, int64(4), p.stats().Skipped)
+
+	close(release)
+	assert.Eventually(t, func() bool { return p.stats().Completed == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, maxRunning["api.example.com"])
+	assert.Equal(t, 0, p.stop())
+}
+
+func TestHostKey(t *testing.T) {
+	assert.Equal(t, "api.example.com", hostKey("https://api.example.com:8443/health"))
+	assert.Equal(t, "10.0.0.1", hostKey("10.0.0.1:123"))
+	assert.Equal(t, "db", hostKey("user:pass@tcp(db:3306)/app"))
+	assert.Equal(t, "example.com", hostKey("example.com"))
+}
+
+func TestInsertChecksQuery(t *testing.T) {
+	loss := 0.5
+	query, args := insertChecksQuery([]models.HealthCheck{
+		{ServiceID: 1, Status: "healthy", Timings: &models.Timings{DNS: 1.5}},
+		{ServiceID: 2, Status: "unhealthy", PacketLoss: &loss},
+	})
+
+	assert.Contains(t, query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13), ($14, ")
+	assert.Contains(t, query, "$26)")
+	assert.Len(t, args, 26)
+	assert.Equal(t, 2, args[13])
+	assert.Equal(t, &loss, args[25])
+}
+
+func TestWriterOverflow(t *testing.T) {
+	w := newWriter(nil, logger.New(), 2, 5, time.Second)
+
+	for i := 1; i <= 6; i++ {
+		w.add(models.HealthCheck{ServiceID: i})
+	}
+
+	// Отбрасываются самые старые результаты, не меньше пачки
+	stats := w.stats()
+	assert.Equal(t, 4, stats.Buffered)
+	assert.Equal(t, int64(2), stats.Dropped)
+	assert.Equal(t, 3, w.buf[0].ServiceID)
+	assert.Len(t, w.full, 1)
+}
+
+func TestWriterRejectedRows(t *testing.T) {
+	w := newWriter(nil, logger.New(), 10, 100, time.Second)
+
+	// Результат удаленного сервиса нарушает внешний ключ, остальные пишутся
+	var written []int
+	down := false
+	w.write = func(batch []models.HealthCheck) error {
+		if down {
+			return errors.New("connection refused")
+		}
+		for _, check := range batch {
+			if check.ServiceID == 2 {
+				return &pq.Error{Code: "23503"}
+			}
+		}
+		for _, check := range batch {
+			written = append(written, check.ServiceID)
+		}
+		return nil
+	}
+
+	for i := 1; i <= 3; i++ {
+		w.add(models.HealthCheck{ServiceID: i})
+	}
+	assert.NoError(t, w.flush())
+	assert.Equal(t, []int{1, 3}, written)
+	stats := w.stats()
+	assert.Equal(t, int64(2), stats.Written)
+	assert.Equal(t, int64(1), stats.Rejected)
+	assert.Equal(t, 0, stats.Buffered)
+
+	// Ошибка соединения временная: результаты остаются в буфере
+	down = true
+	w.add(models.HealthCheck{ServiceID: 4})
+	assert.Error(t, w.flush())
+	assert.Equal(t, 1, w.stats().Buffered)
+	assert.Equal(t, 1, w.stats().Failures)
+
+	down = false
+	assert.NoError(t, w.flush())
+	assert.Equal(t, []int{1, 3, 4}, written)
+}
+
+func TestMergePending(t *testing.T) {
+	now := time.Now()
+	stored := []models.HealthCheck{
+		{Location: "msk", Status: models.StatusHealthy, CheckedAt: now.Add(-2 * time.Second).Round(time.Microsecond)},
+		{Location: "msk", Status: models.StatusHealthy, CheckedAt: now.Add(-3 * time.Second).Round(time.Microsecond)},
+	}
+	pending := []models.HealthCheck{
+		// Уже записан, но еще числится в буфере
+		{Location: "msk", Status: models.StatusHealthy, CheckedAt: now.Add(-2 * time.Second)},
+		{Location: "msk", Status: models.StatusUnhealthy, CheckedAt: now},
+		{Location: "spb", Status: models.StatusUnhealthy, CheckedAt: now.Add(-time.Second)},
+	}
+
+	merged := mergePending(stored, pending, func(check models.HealthCheck) bool {
+		return check.Location == "msk"
+	})
+	if assert.Len(t, merged, 3) {
+		assert.Equal(t, models.StatusUnhealthy, merged[0].Status)
+		assert.True(t, merged[1].CheckedAt.After(merged[2].CheckedAt))
+	}
+}
+
+func TestWriterPending(t *testing.T) {
+	w := newWriter(nil, logger.New(), 10, 100, time.Second)
+	w.write = func(batch []models.HealthCheck) error {
+		// Пишущаяся пачка еще видна
+		assert.Len(t, w.pending(1), 2)
+		return nil
+	}
+
+	w.add(models.HealthCheck{ServiceID: 1})
+	w.add(models.HealthCheck{ServiceID: 2})
+	w.add(models.HealthCheck{ServiceID: 1})
+	assert.Len(t, w.pending(1), 2)
+
+	assert.NoError(t, w.flush())
+	assert.Empty(t, w.pending(1))
+}
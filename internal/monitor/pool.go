@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// PoolStats состояние пула проверок для наблюдения за отставанием
+type PoolStats struct {
+	// Active пул работает: проверки выполняет этот экземпляр
+	Active          bool `json:"active"`
+	Workers         int  `json:"workers"`
+	HostConcurrency int  `json:"host_concurrency"`
+	// Queued проверки в очереди, из них HostLimited ждут лимита своего хоста
+	Queued      int `json:"queued"`
+	HostLimited int `json:"host_limited"`
+	Running     int `json:"running"`
+	// Completed выполнено проверок с запуска пула
+	Completed int64 `json:"completed"`
+	// Skipped пропущено плановых проверок: предыдущая проверка сервиса
+	// еще ждала в очереди или выполнялась
+	Skipped int64 `json:"skipped"`
+	// MaxQueueWaitMs наибольшее ожидание в очереди за последний цикл
+	MaxQueueWaitMs int64 `json:"max_queue_wait_ms"`
+}
+
+type job struct {
+	service  models.Service
+	host     string
+	queuedAt time.Time
+}
+
+// pool выполняет проверки ограниченным числом обработчиков и не больше
+// perHost проверок одного хоста одновременно. У сервиса в пуле не больше
+// одной проверки, поэтому очередь ограничена числом сервисов
+type pool struct {
+	workers int
+	perHost int
+	check   func(ctx context.Context, service models.Service)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []job
+	pending map[int]bool   // сервисы в очереди или на проверке
+	hosts   map[string]int // выполняющиеся проверки по хостам
+	running int
+	closed  bool
+
+	completed int64
+	skipped   int64
+	maxWait   time.Duration
+
+	wg sync.WaitGroup
+}
+
+func newPool(workers, perHost int, check func(ctx context.Context, service models.Service)) *pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if perHost <= 0 {
+		perHost = workers
+	}
+
+	p := &pool{
+		workers: workers,
+		perHost: perHost,
+		check:   check,
+		pending: make(map[int]bool),
+		hosts:   make(map[string]int),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// start запускает обработчики; проверки выполняются с контекстом ctx
+func (p *pool) start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx)
+		}()
+	}
+}
+
+// submit ставит сервисы в очередь плановой проверки и начинает новый цикл
+// статистики. Возвращает, сколько сервисов пропущено, потому что их
+// предыдущая проверка еще не завершилась
+func (p *pool) submit(services []models.Service) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0
+	}
+
+	p.maxWait = 0
+	now := time.Now()
+	skipped := 0
+	for _, service := range services {
+		if p.pending[service.ID] {
+			skipped++
+			continue
+		}
+		p.pending[service.ID] = true
+		p.queue = append(p.queue, job{service: service, host: hostKey(service.URL), queuedAt: now})
+	}
+
+	p.skipped += int64(skipped)
+	p.cond.Broadcast()
+	return skipped
+}
+
+// stop отменяет проверки из очереди, дожидается выполняющихся
+// и возвращает число отмененных
+func (p *pool) stop() int {
+	p.mu.Lock()
+	p.closed = true
+	dropped := len(p.queue)
+	p.queue = nil
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return dropped
+}
+
+func (p *pool) work(ctx context.Context) {
+	for {
+		j, ok := p.next()
+		if !ok {
+			return
+		}
+		p.check(ctx, j.service)
+		p.done(j)
+	}
+}
+
+// next ждет первую проверку из очереди, хост которой не достиг лимита
+func (p *pool) next() (job, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.closed {
+			return job{}, false
+		}
+
+		for i, j := range p.queue {
+			if p.hosts[j.host] >= p.perHost {
+				continue
+			}
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			p.hosts[j.host]++
+			p.running++
+			if wait := time.Since(j.queuedAt); wait > p.maxWait {
+				p.maxWait = wait
+			}
+			return j, true
+		}
+
+		p.cond.Wait()
+	}
+}
+
+func (p *pool) done(j job) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.hosts[j.host]--; p.hosts[j.host] == 0 {
+		delete(p.hosts, j.host)
+	}
+	p.running--
+	p.completed++
+	delete(p.pending, j.service.ID)
+
+	// Освободилось место на хосте: ждущие его проверки могут начаться
+	p.cond.Broadcast()
+}
+
+func (p *pool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	limited := 0
+	for _, j := range p.queue {
+		if p.hosts[j.host] >= p.perHost {
+			limited++
+		}
+	}
+
+	return PoolStats{
+		Active:          !p.closed,
+		Workers:         p.workers,
+		HostConcurrency: p.perHost,
+		Queued:          len(p.queue),
+		HostLimited:     limited,
+		Running:         p.running,
+		Completed:       p.completed,
+		Skipped:         p.skipped,
+		MaxQueueWaitMs:  p.maxWait.Milliseconds(),
+	}
+}
+
+// hostKey хост сервиса для лимита одновременных проверок: из URL,
+// host:port или строки подключения MySQL (user:pass@tcp(host:port)/db)
+func hostKey(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if i := strings.Index(address, "("); i >= 0 {
+		if j := strings.Index(address[i:], ")"); j >= 0 {
+			address = address[i+1 : i+j]
+		}
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
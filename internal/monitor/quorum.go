@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// locationResult последний результат проверки сервиса из одной локации
+type locationResult struct {
+	Location     string
+	Status       string
+	ErrorMessage string
+}
+
+// quorumStatus вычисляет статус сервиса по текущему результату и свежим
+// результатам остальных локаций, включая еще не записанные в БД
+func (s *Service) quorumStatus(serviceID int, current locationResult) (string, string, error) {
+	pending := s.writer.pending(serviceID)
+	maxAge := time.Duration(s.config.ResultMaxAge) * time.Second
+
+	query := `
+		SELECT DISTINCT ON (location) location, status, COALESCE(error_message, ''), checked_at
+		FROM health_checks
+		WHERE service_id = $1
+		  AND location <> $3
+		  AND checked_at >= NOW() - $2 * INTERVAL '1 second'
+		ORDER BY location, checked_at DESC
+	`
+
+	rows, err := s.db.Query(query, serviceID, s.config.ResultMaxAge, current.Location)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	var stored []models.HealthCheck
+	for rows.Next() {
+		var check models.HealthCheck
+		if err := rows.Scan(&check.Location, &check.Status, &check.ErrorMessage, &check.CheckedAt); err != nil {
+			return "", "", err
+		}
+		stored = append(stored, check)
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+
+	checks := mergePending(stored, pending, func(check models.HealthCheck) bool {
+		return check.Location != current.Location && time.Since(check.CheckedAt) <= maxAge
+	})
+
+	// Из каждой локации берем самый свежий результат
+	results := []locationResult{current}
+	seen := map[string]bool{current.Location: true}
+	for _, check := range checks {
+		if seen[check.Location] {
+			continue
+		}
+		seen[check.Location] = true
+		results = append(results, locationResult{
+			Location:     check.Location,
+			Status:       check.Status,
+			ErrorMessage: check.ErrorMessage,
+		})
+	}
+
+	status, message := decideStatus(results, s.config.Quorum)
+	return status, message, nil
+}
+
+func (s *Service) setServiceStatus(serviceID int, status string) error {
+	_, err := s.db.Exec(`UPDATE services SET status = $2 WHERE id = $1`, serviceID, status)
+	return err
+}
+
+// decideStatus сервис недоступен, если сбой видят не меньше quorum локаций
+// (по умолчанию большинство), и деградирован, если столько же локаций видят
+// деградацию или сбой. При одной локации результат совпадает с ее статусом
+func decideStatus(results []locationResult, quorum int) (string, string) {
+	total := len(results)
+	if total == 0 {
+		return models.StatusUnknown, ""
+	}
+
+	needed := quorum
+	if needed <= 0 || needed > total {
+		needed = total/2 + 1
+	}
+
+	var down, impaired []locationResult
+	for _, r := range results {
+		switch r.Status {
+		case models.StatusUnhealthy:
+			down = append(down, r)
+			impaired = append(impaired, r)
+		case models.StatusDegraded:
+			impaired = append(impaired, r)
+		}
+	}
+
+	switch {
+	case len(down) >= needed:
+		return models.StatusUnhealthy, describe(down, total)
+	case len(impaired) >= needed:
+		return models.StatusDegraded, describe(impaired, total)
+	}
+	return models.StatusHealthy, ""
+}
+
+// describe формирует текст причины; для одной локации это просто ее ошибка
+func describe(failed []locationResult, total int) string {
+	if total == 1 {
+		return failed[0].ErrorMessage
+	}
+
+	parts := make([]string, len(failed))
+	for i, r := range failed {
+		parts[i] = r.Location + ": " + r.ErrorMessage
+	}
+	return fmt.Sprintf("из %d из %d локаций (%s)", len(failed), total, strings.Join(parts, "; "))
+}
@@ -0,0 +1,342 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+)
+
+const (
+	// checkColumns колонки health_checks, которые заполняет writer
+	checkColumns = 13
+	// maxBatchSize ограничение PostgreSQL: не больше 65535 параметров в запросе
+	maxBatchSize = 65535 / checkColumns
+	// maxRetryDelay наибольшая пауза между попытками записи, пока БД недоступна
+	maxRetryDelay = 30 * time.Second
+)
+
+// WriterStats состояние буфера результатов проверок
+type WriterStats struct {
+	// Buffered результаты в буфере, еще не записанные в БД; Capacity - предел буфера
+	Buffered int `json:"buffered"`
+	Capacity int `json:"capacity"`
+	// Written записано результатов с запуска
+	Written int64 `json:"written"`
+	// Dropped отброшено результатов: буфер переполнился, пока БД была недоступна
+	Dropped int64 `json:"dropped"`
+	// Rejected отброшено результатов, которые БД не примет никогда,
+	// например результат уже удаленного сервиса
+	Rejected int64 `json:"rejected"`
+	// Failures неудачных попыток записи подряд; LastError - ошибка последней
+	Failures  int        `json:"failures"`
+	LastError string     `json:"last_error,omitempty"`
+	LastFlush *time.Time `json:"last_flush,omitempty"`
+}
+
+// writer копит результаты проверок и записывает их в health_checks пачками:
+// одним многострочным INSERT при наборе batchSize строк или раз в interval.
+// Буфер ограничен capacity строками. Пока БД недоступна, строки остаются
+// в буфере, а попытки повторяются с растущей паузой; при переполнении
+// отбрасываются самые старые строки - для статуса и алертов важнее свежие.
+// Если БД отвергла пачку из-за данных, строки пишутся по одной, а
+// отвергнутые отбрасываются: повтор их не исправит
+type writer struct {
+	db        *database.DB
+	logger    *logger.Logger
+	write     func(batch []models.HealthCheck) error
+	batchSize int
+	capacity  int
+	interval  time.Duration
+
+	mu        sync.Mutex
+	buf       []models.HealthCheck
+	inflight  []models.HealthCheck // пачка, которая сейчас пишется
+	full      chan struct{}        // набралась пачка
+	written   int64
+	dropped   int64
+	rejected  int64
+	failures  int
+	lastError string
+	lastFlush time.Time
+}
+
+func newWriter(db *database.DB, logger *logger.Logger, batchSize, capacity int, interval time.Duration) *writer {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if batchSize > maxBatchSize {
+		batchSize = maxBatchSize
+	}
+	if capacity < batchSize {
+		capacity = batchSize
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	w := &writer{
+		db:        db,
+		logger:    logger,
+		batchSize: batchSize,
+		capacity:  capacity,
+		interval:  interval,
+		full:      make(chan struct{}, 1),
+	}
+	w.write = w.insert
+	return w
+}
+
+// add ставит результат в буфер и не блокируется
+func (w *writer) add(check models.HealthCheck) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, check)
+	w.trim()
+
+	if len(w.buf) >= w.batchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// trim отбрасывает самые старые строки сверх capacity. Вызывается под mu
+func (w *writer) trim() {
+	excess := len(w.buf) - w.capacity
+	if excess <= 0 {
+		return
+	}
+	// Отбрасываем не меньше пачки, чтобы не сдвигать буфер на каждую строку
+	if excess < w.batchSize {
+		excess = w.batchSize
+	}
+	if excess > len(w.buf) {
+		excess = len(w.buf)
+	}
+
+	w.buf = append(w.buf[:0], w.buf[excess:]...)
+	w.dropped += int64(excess)
+	w.logger.Errorf("Буфер результатов проверок переполнен: отброшено %d старых результатов (всего %d)",
+		excess, w.dropped)
+}
+
+// run записывает буфер по таймеру и при наборе пачки до отмены ctx,
+// затем пытается записать остаток
+func (w *writer) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var retryAt time.Time
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-ctx.Done():
+			if err := w.flush(); err != nil {
+				w.mu.Lock()
+				lost := len(w.buf)
+				w.mu.Unlock()
+				w.logger.Errorf("Не удалось записать результаты проверок при остановке, потеряно %d: %v", lost, err)
+			}
+			return
+		}
+
+		// Пока БД недоступна, не пытаемся писать чаще паузы
+		if time.Now().Before(retryAt) {
+			continue
+		}
+		if err := w.flush(); err != nil {
+			retryAt = time.Now().Add(w.retryDelay())
+			continue
+		}
+		retryAt = time.Time{}
+	}
+}
+
+// retryDelay пауза перед следующей попыткой: удваивается с каждой неудачей
+func (w *writer) retryDelay() time.Duration {
+	w.mu.Lock()
+	failures := w.failures
+	w.mu.Unlock()
+
+	delay := w.interval
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// flush записывает буфер пачками до опустошения или первой ошибки.
+// Незаписанная пачка возвращается в начало буфера
+func (w *writer) flush() error {
+	for {
+		w.mu.Lock()
+		n := len(w.buf)
+		if n == 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		if n > w.batchSize {
+			n = w.batchSize
+		}
+		batch := append([]models.HealthCheck(nil), w.buf[:n]...)
+		w.buf = append(w.buf[:0], w.buf[n:]...)
+		w.inflight = batch
+		w.mu.Unlock()
+
+		var written, rejected int
+		err := w.write(batch)
+		switch {
+		case err == nil:
+			written = len(batch)
+		case rejectedByData(err):
+			written, rejected, batch, err = w.writeEach(batch)
+		}
+
+		w.mu.Lock()
+		w.inflight = nil
+		w.written += int64(written)
+		w.rejected += int64(rejected)
+		if err != nil {
+			if w.failures == 0 {
+				w.logger.Errorf("Ошибка записи результатов проверок, результаты сохранены в буфере: %v", err)
+			}
+			w.failures++
+			w.lastError = err.Error()
+			w.buf = append(batch, w.buf...)
+			w.trim()
+			w.mu.Unlock()
+			return err
+		}
+		if w.failures > 0 {
+			w.logger.Infof("Запись результатов проверок восстановлена после %d неудачных попыток", w.failures)
+		}
+		w.failures = 0
+		w.lastError = ""
+		w.lastFlush = time.Now()
+		w.mu.Unlock()
+	}
+}
+
+// writeEach пишет пачку по одной строке, отбрасывая отвергнутые. При ошибке
+// другого рода возвращает ее и строки, которые еще предстоит записать
+func (w *writer) writeEach(batch []models.HealthCheck) (written, rejected int, rest []models.HealthCheck, err error) {
+	for i, check := range batch {
+		err := w.write([]models.HealthCheck{check})
+		switch {
+		case err == nil:
+			written++
+		case rejectedByData(err):
+			rejected++
+			w.logger.Errorf("Результат проверки сервиса %d (%s, %s) отброшен: %v",
+				check.ServiceID, check.Location, check.CheckedAt.Format(time.RFC3339), err)
+		default:
+			return written, rejected, batch[i:], err
+		}
+	}
+	return written, rejected, nil, nil
+}
+
+// rejectedByData сообщает, что БД отвергла данные: классы SQLSTATE 22
+// (ошибка данных) и 23 (нарушение ограничений). Ошибки соединения и сервера
+// временные, их стоит повторить
+func rejectedByData(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	class := pqErr.Code.Class()
+	return class == "22" || class == "23"
+}
+
+// pending возвращает результаты сервиса, еще не записанные в БД
+func (w *writer) pending(serviceID int) []models.HealthCheck {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var checks []models.HealthCheck
+	for _, list := range [][]models.HealthCheck{w.inflight, w.buf} {
+		for _, check := range list {
+			if check.ServiceID == serviceID {
+				checks = append(checks, check)
+			}
+		}
+	}
+	return checks
+}
+
+func (w *writer) insert(batch []models.HealthCheck) error {
+	query, args := insertChecksQuery(batch)
+	_, err := w.db.Exec(query, args...)
+	return err
+}
+
+func (w *writer) stats() WriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := WriterStats{
+		Buffered:  len(w.buf),
+		Capacity:  w.capacity,
+		Written:   w.written,
+		Dropped:   w.dropped,
+		Rejected:  w.rejected,
+		Failures:  w.failures,
+		LastError: w.lastError,
+	}
+	if !w.lastFlush.IsZero() {
+		lastFlush := w.lastFlush
+		stats.LastFlush = &lastFlush
+	}
+	return stats
+}
+
+// insertChecksQuery строит многострочный INSERT для пачки результатов
+func insertChecksQuery(checks []models.HealthCheck) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString(`INSERT INTO health_checks (service_id, status, response_time, error_message, checked_at, location, ` +
+		`dns_time, connect_time, tls_time, ttfb, transfer_time, service_status, packet_loss) VALUES `)
+
+	args := make([]interface{}, 0, len(checks)*checkColumns)
+	for i, check := range checks {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := 1; j <= checkColumns; j++ {
+			if j > 1 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", i*checkColumns+j)
+		}
+		b.WriteByte(')')
+
+		var dns, connect, tlsTime, ttfb, transfer sql.NullFloat64
+		if t := check.Timings; t != nil {
+			dns = sql.NullFloat64{Float64: t.DNS, Valid: true}
+			connect = sql.NullFloat64{Float64: t.Connect, Valid: true}
+			tlsTime = sql.NullFloat64{Float64: t.TLS, Valid: true}
+			ttfb = sql.NullFloat64{Float64: t.TTFB, Valid: true}
+			transfer = sql.NullFloat64{Float64: t.Transfer, Valid: true}
+		}
+		args = append(args, check.ServiceID, check.Status, check.ResponseTime, check.ErrorMessage, check.CheckedAt,
+			check.Location, dns, connect, tlsTime, ttfb, transfer, check.ServiceStatus, check.PacketLoss)
+	}
+
+	return b.String(), args
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/models"
+)
+
+// groupMessage объединяет оповещения в одно сообщение со списком алертов
+// и затронутых сервисов
+func (n *Notifier) groupMessage(channel models.Channel, msgs []Message) (Message, error) {
+	alerts := make([]models.Alert, 0, len(msgs))
+	for _, msg := range msgs {
+		alerts = append(alerts, msg.Alert)
+	}
+
+	items, err := n.alertItems(alerts)
+	if err != nil {
+		return Message{}, err
+	}
+
+	data := GroupData{
+		Event:        msgs[0].Event,
+		Alerts:       items,
+		Services:     services(items),
+		DashboardURL: n.config.DashboardURL,
+	}
+
+	msg, err := RenderGroup(channel, data)
+	if err != nil {
+		n.logger.Errorf("Ошибка шаблона канала %s, используется шаблон по умолчанию: %v", channel.Name, err)
+		return RenderGroup(models.Channel{}, data)
+	}
+	return msg, nil
+}
+
+// enqueueDigests ставит в очередь сводки каналов, у которых подошло время.
+// Сводка содержит алерты, оповещения о которых канал получал: открытые и
+// разрешенные после предыдущей сводки. Пустая сводка не отправляется
+func (n *Notifier) enqueueDigests() {
+	rows, err := n.db.Query(`SELECT ` + ChannelColumns + ` FROM channels ORDER BY id`)
+	if err != nil {
+		n.logger.Error("Ошибка получения каналов:", err)
+		return
+	}
+
+	var channels []models.Channel
+	for rows.Next() {
+		var channel models.Channel
+		if err := ScanChannel(rows, &channel); err != nil {
+			n.logger.Error("Ошибка чтения канала:", err)
+			rows.Close()
+			return
+		}
+		if channel.Config.DigestIntervalMinutes > 0 {
+			channels = append(channels, channel)
+		}
+	}
+	rows.Close()
+
+	for _, channel := range channels {
+		if err := n.enqueueDigest(channel); err != nil {
+			n.logger.Errorf("Ошибка формирования сводки канала %s: %v", channel.Name, err)
+		}
+	}
+}
+
+func (n *Notifier) enqueueDigest(channel models.Channel) error {
+	// Отметка времени сводки одновременно проверяет, что интервал прошел
+	query := `
+		UPDATE channels c SET digest_sent_at = NOW()
+		FROM (SELECT id, digest_sent_at FROM channels WHERE id = $1 FOR UPDATE) prev
+		WHERE c.id = prev.id
+		  AND (prev.digest_sent_at IS NULL OR prev.digest_sent_at <= NOW() - $2 * INTERVAL '1 minute')
+		RETURNING COALESCE(prev.digest_sent_at, NOW() - $2 * INTERVAL '1 minute')
+	`
+
+	var since time.Time
+	rows, err := n.db.Query(query, channel.ID, channel.Config.DigestIntervalMinutes)
+	if err != nil {
+		return err
+	}
+	due := rows.Next()
+	if due {
+		err = rows.Scan(&since)
+	}
+	rows.Close()
+	if err != nil || !due {
+		return err
+	}
+
+	open, err := n.digestAlerts(channel.ID, false, since)
+	if err != nil {
+		return err
+	}
+	resolved, err := n.digestAlerts(channel.ID, true, since)
+	if err != nil {
+		return err
+	}
+	if len(open) == 0 && len(resolved) == 0 {
+		return nil
+	}
+
+	data := DigestData{
+		Since:        since,
+		Open:         open,
+		Resolved:     resolved,
+		DashboardURL: n.config.DashboardURL,
+	}
+
+	msg, err := RenderDigest(channel, data)
+	if err != nil {
+		n.logger.Errorf("Ошибка шаблона канала %s, используется шаблон по умолчанию: %v", channel.Name, err)
+		if msg, err = RenderDigest(models.Channel{}, data); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.db.Exec(`
+		INSERT INTO notification_outbox (alert_id, channel_id, recipient, event, payload)
+		VALUES (NULL, $1, $2, $3, $4)
+	`, channel.ID, channel.Name, EventDigest, payload)
+	return err
+}
+
+// digestAlerts алерты, оповещения о которых попали в сводку канала:
+// открытые или разрешенные после since
+func (n *Notifier) digestAlerts(channelID int, resolved bool, since time.Time) ([]AlertItem, error) {
+	query := `
+		SELECT ` + alerting.AlertColumns + `
+		FROM alerts
+		WHERE is_resolved = $2 AND (NOT $2 OR resolved_at > $3) AND id IN (
+			SELECT alert_id FROM notification_outbox WHERE channel_id = $1 AND status = $4
+		)
+		ORDER BY created_at
+	`
+
+	rows, err := n.db.Query(query, channelID, resolved, since, StatusDigest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		if err := alerting.ScanAlert(rows, &alert); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return n.alertItems(alerts)
+}
+
+// alertItems дополняет алерты именами сервисов и длительностью
+func (n *Notifier) alertItems(alerts []models.Alert) ([]AlertItem, error) {
+	var ids []int64
+	for _, alert := range alerts {
+		if alert.ServiceID != 0 {
+			ids = append(ids, int64(alert.ServiceID))
+		}
+	}
+
+	names := make(map[int]string)
+	if len(ids) > 0 {
+		rows, err := n.db.Query(`SELECT id, name FROM services WHERE id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return nil, err
+			}
+			names[id] = name
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	items := make([]AlertItem, 0, len(alerts))
+	for _, alert := range alerts {
+		item := AlertItem{Alert: alert, Service: names[alert.ServiceID], Duration: now.Sub(alert.CreatedAt)}
+		if alert.ResolvedAt != nil {
+			item.Duration = alert.ResolvedAt.Sub(alert.CreatedAt)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// services имена затронутых сервисов без повторов, по алфавиту
+func services(items []AlertItem) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, item := range items {
+		if item.Service != "" && !seen[item.Service] {
+			seen[item.Service] = true
+			names = append(names, item.Service)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
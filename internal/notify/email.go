@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"service-monitor/internal/config"
+	"service-monitor/internal/models"
+)
+
+// emailSender отправляет оповещение письмом через SMTP сервер из конфигурации
+type emailSender struct {
+	addr     string
+	username string
+	password string
+	from     string
+}
+
+func newEmailSender(cfg *config.Config) *emailSender {
+	return &emailSender{
+		addr:     cfg.SMTPAddr,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.SMTPFrom,
+	}
+}
+
+func (e *emailSender) Send(ctx context.Context, channel models.Channel, msg Message) (int, error) {
+	if e.addr == "" {
+		return 0, fmt.Errorf("SMTP не настроен")
+	}
+	if len(channel.Config.To) == 0 {
+		return 0, fmt.Errorf("не указаны получатели")
+	}
+
+	var auth smtp.Auth
+	if e.username != "" {
+		host, _, _ := net.SplitHostPort(e.addr)
+		auth = smtp.PlainAuth("", e.username, e.password, host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(channel.Config.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(msg.Text)
+	body.WriteString("\r\n")
+
+	// net/smtp не принимает контекст, поэтому ждем отправку не дольше его
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.addr, auth, e.from, channel.Config.To, []byte(body.String()))
+	}()
+
+	select {
+	case err := <-done:
+		// Код ответа SMTP известен только при отказе сервера
+		var smtpErr *textproto.Error
+		if errors.As(err, &smtpErr) {
+			return smtpErr.Code, err
+		}
+		return 0, err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
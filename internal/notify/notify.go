@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"service-monitor/internal/config"
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+	"service-monitor/internal/secrets"
+)
+
+// Event события оповещений
+const (
+	EventFiring   = "firing"
+	EventResolved = "resolved"
+	EventDigest   = "digest"
+)
+
+// Message оповещение об алерте
+type Message struct {
+	Event   string       `json:"event"`
+	Subject string       `json:"subject"`
+	Text    string       `json:"text"`
+	Alert   models.Alert `json:"alert"`
+	// Alerts алерты группового сообщения или сводки; Alert у них пустой
+	Alerts []models.Alert `json:"alerts,omitempty"`
+}
+
+// Sender доставляет оповещение в канал определенного типа. Возвращает код
+// ответа получателя (HTTP статус, код SMTP), если он есть, иначе 0
+type Sender interface {
+	Send(ctx context.Context, channel models.Channel, msg Message) (int, error)
+}
+
+// Notifier ставит оповещения в очередь (outbox) и доставляет их в каналы
+// с повторами, записывая каждую попытку
+type Notifier struct {
+	config  *config.Config
+	db      *database.DB
+	secrets *secrets.Store
+	senders map[string]Sender
+	logger  *logger.Logger
+}
+
+func NewNotifier(cfg *config.Config, db *database.DB, secrets *secrets.Store, logger *logger.Logger) *Notifier {
+	return &Notifier{
+		config:  cfg,
+		db:      db,
+		secrets: secrets,
+		senders: map[string]Sender{
+			models.ChannelWebhook: newWebhookSender(),
+			models.ChannelEmail:   newEmailSender(cfg),
+		},
+		logger: logger,
+	}
+}
+
+// ChannelColumns список колонок channels в порядке, который ожидает ScanChannel
+const ChannelColumns = `id, name, type, config, created_at`
+
+// ScanChannel читает строку, выбранную через ChannelColumns
+func ScanChannel(row database.Scanner, channel *models.Channel) error {
+	return row.Scan(&channel.ID, &channel.Name, &channel.Type, &channel.Config, &channel.CreatedAt)
+}
+
+// Enqueue формирует оповещение по шаблону канала и сохраняет его в outbox;
+// доставляет его Run. recipient - кому адресовано оповещение (дежурный или
+// канал), для журнала доставки; пустой - имя канала.
+// Оповещения канала со сводками только записываются и попадают в сводку,
+// оповещения канала с окном группировки присоединяются к открытой группе
+func (n *Notifier) Enqueue(channelID int, recipient, event string, alert models.Alert) error {
+	channel, err := n.channel(channelID)
+	if err != nil {
+		return err
+	}
+
+	if recipient == "" {
+		recipient = channel.Name
+	}
+
+	data, err := n.templateData(event, alert)
+	if err != nil {
+		return err
+	}
+
+	msg, err := Render(channel, data)
+	if err != nil {
+		// Ошибка в пользовательском шаблоне не должна терять оповещение
+		n.logger.Errorf("Ошибка шаблона канала %s, используется шаблон по умолчанию: %v", channel.Name, err)
+		msg, err = Render(models.Channel{}, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	status := StatusPending
+	if channel.Config.DigestIntervalMinutes > 0 {
+		status = StatusDigest
+	}
+
+	// Группа открыта, пока ее первое оповещение ждет доставки: новое
+	// оповещение получает то же время попытки и уходит вместе с ним
+	query := `
+		INSERT INTO notification_outbox (alert_id, channel_id, recipient, event, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $7 > 0 THEN COALESCE(
+			(SELECT MIN(next_attempt_at) FROM notification_outbox
+			 WHERE channel_id = $2 AND recipient = $3 AND event = $4 AND status = $6
+			   AND attempts = 0 AND next_attempt_at > NOW()),
+			NOW() + $7 * INTERVAL '1 second') ELSE NOW() END)
+	`
+
+	_, err = n.db.Exec(query, alert.ID, channelID, recipient, event, payload, status, channel.Config.GroupWindowSeconds)
+	return err
+}
+
+// EnqueueGroup ставит в outbox одно сообщение о нескольких алертах, например
+// собранных маршрутом за group_wait. Один алерт ставится как через Enqueue,
+// каналу со сводками алерты ставятся по отдельности: они попадут в сводку
+func (n *Notifier) EnqueueGroup(channelID int, event string, alerts []models.Alert) error {
+	channel, err := n.channel(channelID)
+	if err != nil {
+		return err
+	}
+
+	if len(alerts) == 1 || channel.Config.DigestIntervalMinutes > 0 {
+		for _, alert := range alerts {
+			if err := n.Enqueue(channelID, "", event, alert); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	msgs := make([]Message, len(alerts))
+	for i, alert := range alerts {
+		msgs[i] = Message{Event: event, Alert: alert}
+	}
+	msg, err := n.groupMessage(channel, msgs)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// Групповое сообщение не относится к одному алерту, как и сводка
+	_, err = n.db.Exec(`
+		INSERT INTO notification_outbox (alert_id, channel_id, recipient, event, payload)
+		VALUES (NULL, $1, $2, $3, $4)
+	`, channel.ID, channel.Name, event, payload)
+	return err
+}
+
+// channel загружает канал по ID
+func (n *Notifier) channel(id int) (models.Channel, error) {
+	var channel models.Channel
+	err := ScanChannel(n.db.QueryRow(`SELECT `+ChannelColumns+` FROM channels WHERE id = $1`, id), &channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return channel, fmt.Errorf("канал %d не найден", id)
+	}
+	return channel, err
+}
+
+// templateData собирает данные для шаблона: сервис, его последнюю проверку
+// и длительность алерта
+func (n *Notifier) templateData(event string, alert models.Alert) (TemplateData, error) {
+	data := TemplateData{
+		Event:        event,
+		Alert:        alert,
+		Duration:     time.Since(alert.CreatedAt),
+		DashboardURL: n.config.DashboardURL,
+	}
+	if alert.ResolvedAt != nil {
+		data.Duration = alert.ResolvedAt.Sub(alert.CreatedAt)
+	}
+
+	if alert.ServiceID == 0 {
+		return data, nil
+	}
+
+	service, err := n.db.GetService(alert.ServiceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	// Шаблон может вывести URL сервиса, учетные данные в нем скрываются
+	redacted := secrets.RedactService(*service)
+	data.Service = &redacted
+
+	query := `
+		SELECT id, service_id, status, response_time, COALESCE(error_message, ''), checked_at, location
+		FROM health_checks
+		WHERE service_id = $1
+		ORDER BY checked_at DESC
+		LIMIT 1
+	`
+
+	var check models.HealthCheck
+	err = n.db.QueryRow(query, alert.ServiceID).Scan(&check.ID, &check.ServiceID, &check.Status,
+		&check.ResponseTime, &check.ErrorMessage, &check.CheckedAt, &check.Location)
+	if errors.Is(err, sql.ErrNoRows) {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	data.Check = &check
+
+	return data, nil
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/models"
+)
+
+func TestBackoff(t *testing.T) {
+	base := 10 * time.Second
+	max := time.Minute
+
+	assert.Equal(t, 10*time.Second, backoff(1, base, max))
+	assert.Equal(t, 20*time.Second, backoff(2, base, max))
+	assert.Equal(t, 40*time.Second, backoff(3, base, max))
+	assert.Equal(t, time.Minute, backoff(4, base, max))
+	assert.Equal(t, time.Minute, backoff(100, base, max))
+
+	assert.Equal(t, 80*time.Second, backoff(4, base, 0))
+	assert.Equal(t, time.Duration(0), backoff(3, 0, max))
+}
+
+func templateData(event string) TemplateData {
+	created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	return TemplateData{
+		Event: event,
+		Alert: models.Alert{
+			ID:        7,
+			ServiceID: 3,
+			Message:   "Сервис api недоступен: HTTP 503",
+			Severity:  models.SeverityError,
+			Kind:      models.AlertKindDown,
+			CreatedAt: created,
+		},
+		Service:      &models.Service{ID: 3, Name: "api", URL: "https://api.example.com/health"},
+		Check:        &models.HealthCheck{Status: models.StatusUnhealthy, ResponseTime: 120, ErrorMessage: "HTTP 503", Location: "local"},
+		Duration:     5*time.Minute + 300*time.Millisecond,
+		DashboardURL: "http://monitor.example.com",
+	}
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	msg, err := Render(models.Channel{}, templateData(EventFiring))
+	assert.NoError(t, err)
+	assert.Equal(t, "[ERROR] Сервис api недоступен: HTTP 503", msg.Subject)
+	assert.Contains(t, msg.Text, "Последняя проверка: unhealthy, 120 мс, HTTP 503 (local)")
+	assert.Contains(t, msg.Text, "длится 5m0s")
+	assert.Contains(t, msg.Text, "Дашборд: http://monitor.example.com")
+
+	msg, err = Render(models.Channel{Config: models.ChannelConfig{Language: "en"}}, templateData(EventResolved))
+	assert.NoError(t, err)
+	assert.Equal(t, "[RESOLVED] down alert #7 for api", msg.Subject)
+	assert.Contains(t, msg.Text, "Resolved after 5m0s")
+	assert.Equal(t, EventResolved, msg.Event)
+}
+
+func TestRenderWithoutService(t *testing.T) {
+	data := templateData(EventFiring)
+	data.Service = nil
+	data.Check = nil
+
+	for language := range DefaultTemplates {
+		for _, event := range []string{EventFiring, EventResolved} {
+			data.Event = event
+			msg, err := Render(models.Channel{Config: models.ChannelConfig{Language: language}}, data)
+			assert.NoError(t, err, language+" "+event)
+			assert.NotContains(t, msg.Text, "api.example.com")
+		}
+	}
+}
+
+func TestRenderCustomTemplate(t *testing.T) {
+	channel := models.Channel{Config: models.ChannelConfig{
+		FiringTemplate: `{{.Service.Name}} упал {{duration .Duration}} назад`,
+	}}
+
+	msg, err := Render(channel, templateData(EventFiring))
+	assert.NoError(t, err)
+	assert.Equal(t, "api упал 5m0s назад", msg.Text)
+
+	// Для resolved используется шаблон по умолчанию
+	msg, err = Render(channel, templateData(EventResolved))
+	assert.NoError(t, err)
+	assert.Contains(t, msg.Subject, "[РЕШЕНО]")
+}
+
+func TestValidateTemplates(t *testing.T) {
+	assert.NoError(t, ValidateTemplates(models.ChannelConfig{}))
+	assert.NoError(t, ValidateTemplates(models.ChannelConfig{Language: "en"}))
+	assert.Error(t, ValidateTemplates(models.ChannelConfig{Language: "de"}))
+	assert.Error(t, ValidateTemplates(models.ChannelConfig{ResolvedTemplate: "{{.Alert.ID"}))
+	assert.Error(t, ValidateTemplates(models.ChannelConfig{FiringTemplate: "{{unknown .Alert}}"}))
+	assert.Error(t, ValidateTemplates(models.ChannelConfig{DigestTemplate: "{{range .Open}}"}))
+}
+
+func TestRenderGroup(t *testing.T) {
+	data := GroupData{
+		Event: EventFiring,
+		Alerts: []AlertItem{
+			{Alert: models.Alert{ID: 1, Message: "Сервис api недоступен", Severity: models.SeverityError}, Service: "api"},
+			{Alert: models.Alert{ID: 2, Message: "Сервис db недоступен", Severity: models.SeverityError}, Service: "db"},
+			{Alert: models.Alert{ID: 3, Message: "Группа core: p95 > 800", Severity: models.SeverityWarning}},
+		},
+		Services:     []string{"api", "db"},
+		DashboardURL: "http://monitor.example.com",
+	}
+
+	msg, err := RenderGroup(models.Channel{}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ГРУППА] Сработало алертов: 3 (api, db)", msg.Subject)
+	assert.Contains(t, msg.Text, "- [ERROR] Сервис db недоступен (db)")
+	assert.Contains(t, msg.Text, "- [WARNING] Группа core: p95 > 800\n")
+	assert.Len(t, msg.Alerts, 3)
+	assert.Equal(t, EventFiring, msg.Event)
+
+	data.Event = EventResolved
+	msg, err = RenderGroup(models.Channel{Config: models.ChannelConfig{Language: "en"}}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[RESOLVED] 3 alerts resolved (api, db)", msg.Subject)
+}
+
+func TestRenderDigest(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := DigestData{
+		Since: since,
+		Open: []AlertItem{
+			{Alert: models.Alert{ID: 1, Message: "Сервис api недоступен", Severity: models.SeverityWarning}, Service: "api", Duration: 2 * time.Hour},
+		},
+		DashboardURL: "http://monitor.example.com",
+	}
+
+	msg, err := RenderDigest(models.Channel{}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[СВОДКА] Открытых алертов: 1, разрешено с 2024-01-01 12:00:00 UTC: 0", msg.Subject)
+	assert.Contains(t, msg.Text, "- [WARNING] Сервис api недоступен (api), длится 2h0m0s")
+	assert.NotContains(t, msg.Text, "Разрешены:")
+	assert.Equal(t, EventDigest, msg.Event)
+}
@@ -0,0 +1,329 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// deliverInterval период выборки оповещений, которые пора доставить
+	deliverInterval = 5 * time.Second
+	// deliverBatch сколько оповещений забирается за раз
+	deliverBatch = 50
+	// deliveryTimeout ограничение на одну попытку доставки
+	deliveryTimeout = 30 * time.Second
+	// claimLease на это время забранное оповещение скрыто от других выборок;
+	// если экземпляр упал посреди доставки, оповещение вернется в очередь
+	claimLease = 2 * deliveryTimeout
+)
+
+// OutboxStatus статусы оповещений в очереди
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	// StatusDigest оповещение канала со сводками: отдельно не доставляется
+	StatusDigest = "digest"
+)
+
+// outboxItem оповещение, забранное из очереди на доставку
+type outboxItem struct {
+	id        int
+	grouped   bool // сообщение уже собрано из нескольких алертов: групповое или сводка
+	channelID int
+	recipient string
+	event     string
+	attempts  int
+	payload   []byte
+}
+
+// Run доставляет оповещения из outbox до отмены ctx
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(deliverInterval)
+	defer ticker.Stop()
+
+	for {
+		// Полная выборка значит, что в очереди могут остаться еще оповещения
+		for n.deliverDue(ctx) == deliverBatch && ctx.Err() == nil {
+		}
+		n.enqueueDigests()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue доставляет оповещения, время попытки которых наступило,
+// и возвращает их количество
+func (n *Notifier) deliverDue(ctx context.Context) int {
+	items, err := n.claim()
+	if err != nil {
+		n.logger.Error("Ошибка выборки оповещений:", err)
+		return 0
+	}
+
+	for _, batch := range n.groups(items) {
+		if ctx.Err() != nil {
+			break
+		}
+		n.deliver(ctx, batch)
+	}
+	return len(items)
+}
+
+// groups делит забранные оповещения на отправки: оповещения канала с окном
+// группировки для одного получателя и события уходят одним сообщением,
+// остальные - по одному
+func (n *Notifier) groups(items []outboxItem) [][]outboxItem {
+	type groupKey struct {
+		channelID int
+		recipient string
+		event     string
+	}
+
+	grouping := make(map[int]bool)
+	index := make(map[groupKey]int)
+	var batches [][]outboxItem
+	for _, item := range items {
+		// Собранное сообщение уходит как есть
+		if item.grouped {
+			batches = append(batches, []outboxItem{item})
+			continue
+		}
+
+		enabled, ok := grouping[item.channelID]
+		if !ok {
+			// Канал не найден - оповещение уйдет отдельно и получит ошибку при отправке
+			channel, err := n.channel(item.channelID)
+			enabled = err == nil && channel.Config.GroupWindowSeconds > 0
+			grouping[item.channelID] = enabled
+		}
+
+		key := groupKey{channelID: item.channelID, recipient: item.recipient, event: item.event}
+		if i, ok := index[key]; ok && enabled {
+			batches[i] = append(batches[i], item)
+			continue
+		}
+		if enabled {
+			index[key] = len(batches)
+		}
+		batches = append(batches, []outboxItem{item})
+	}
+	return batches
+}
+
+// claim забирает оповещения из очереди, откладывая их следующую попытку
+// на claimLease, чтобы параллельная выборка их не взяла
+func (n *Notifier) claim() ([]outboxItem, error) {
+	query := `
+		UPDATE notification_outbox
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, alert_id IS NULL, channel_id, recipient, event, attempts, payload
+	`
+
+	rows, err := n.db.Query(query, StatusPending, int64(claimLease.Seconds()), deliverBatch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []outboxItem
+	for rows.Next() {
+		var item outboxItem
+		if err := rows.Scan(&item.id, &item.grouped, &item.channelID, &item.recipient, &item.event, &item.attempts, &item.payload); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// deliver выполняет одну попытку доставки, записывает ее в журнал каждого
+// оповещения и назначает следующую попытку с экспоненциальной задержкой.
+// Несколько оповещений отправляются одним групповым сообщением
+func (n *Notifier) deliver(ctx context.Context, batch []outboxItem) {
+	var items []outboxItem
+	var msgs []Message
+	for _, item := range batch {
+		var msg Message
+		if err := json.Unmarshal(item.payload, &msg); err != nil {
+			n.logger.Errorf("Ошибка чтения оповещения %d: %v", item.id, err)
+			n.discard(item, fmt.Errorf("оповещение не читается: %w", err))
+			continue
+		}
+		items = append(items, item)
+		msgs = append(msgs, msg)
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	started := time.Now()
+	code, err := n.send(ctx, items[0].channelID, msgs)
+	subject := describe(msgs)
+
+	// Остановка экземпляра не считается неудачной попыткой: оповещение
+	// вернется в очередь по истечении claimLease
+	if ctx.Err() != nil {
+		return
+	}
+
+	var statusCode sql.NullInt64
+	if code != 0 {
+		statusCode = sql.NullInt64{Int64: int64(code), Valid: true}
+	}
+	var errorMessage string
+	if err != nil {
+		errorMessage = err.Error()
+	}
+
+	for _, item := range items {
+		attempt := item.attempts + 1
+
+		_, logErr := n.db.Exec(`
+			INSERT INTO notification_attempts (outbox_id, attempt, attempted_at, duration_ms, status_code, error, success)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, item.id, attempt, started, time.Since(started).Milliseconds(), statusCode, errorMessage, err == nil)
+		if logErr != nil {
+			n.logger.Error("Ошибка записи попытки доставки:", logErr)
+		}
+
+		status := StatusPending
+		nextAttempt := started.Add(backoff(attempt, n.retryBase(), n.retryMax()))
+		switch {
+		case err == nil:
+			status = StatusSent
+		case attempt >= n.config.NotifyMaxAttempts:
+			status = StatusFailed
+		}
+
+		_, updateErr := n.db.Exec(`
+			UPDATE notification_outbox
+			SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5,
+			    sent_at = CASE WHEN $2 = 'sent' THEN CURRENT_TIMESTAMP END
+			WHERE id = $1
+		`, item.id, status, attempt, nextAttempt, errorMessage)
+		if updateErr != nil {
+			n.logger.Error("Ошибка обновления оповещения:", updateErr)
+		}
+	}
+
+	attempt := items[0].attempts + 1
+	switch {
+	case err == nil:
+		n.logger.Infof("Оповещение (%s, %s) доставлено в канал %d", subject, msgs[0].Event, items[0].channelID)
+	case attempt >= n.config.NotifyMaxAttempts:
+		n.logger.Errorf("Оповещение (%s) не доставлено за %d попыток: %v", subject, attempt, err)
+	default:
+		n.logger.Errorf("Ошибка доставки оповещения (%s, попытка %d): %v", subject, attempt, err)
+	}
+}
+
+// discard завершает оповещение, которое нельзя доставить ни с какой попытки:
+// записывает попытку с ошибкой и переводит его в failed, иначе оно
+// возвращалось бы в очередь после каждого claimLease
+func (n *Notifier) discard(item outboxItem, reason error) {
+	attempt := item.attempts + 1
+
+	_, err := n.db.Exec(`
+		INSERT INTO notification_attempts (outbox_id, attempt, attempted_at, duration_ms, status_code, error, success)
+		VALUES ($1, $2, NOW(), 0, NULL, $3, false)
+	`, item.id, attempt, reason.Error())
+	if err != nil {
+		n.logger.Error("Ошибка записи попытки доставки:", err)
+	}
+
+	_, err = n.db.Exec(`
+		UPDATE notification_outbox SET status = $2, attempts = $3, last_error = $4
+		WHERE id = $1
+	`, item.id, StatusFailed, attempt, reason.Error())
+	if err != nil {
+		n.logger.Error("Ошибка обновления оповещения:", err)
+	}
+}
+
+// describe кратко описывает отправку для журнала
+func describe(msgs []Message) string {
+	switch {
+	case len(msgs) > 1:
+		return fmt.Sprintf("группа алертов: %d", len(msgs))
+	case msgs[0].Event == EventDigest:
+		return "сводка"
+	default:
+		return fmt.Sprintf("алерт %d", msgs[0].Alert.ID)
+	}
+}
+
+// send находит канал и отправляет в него оповещение; несколько
+// оповещений объединяются в групповое сообщение
+func (n *Notifier) send(ctx context.Context, channelID int, msgs []Message) (int, error) {
+	channel, err := n.channel(channelID)
+	if err != nil {
+		return 0, err
+	}
+	channel, concealer, err := n.secrets.ResolveChannel(channel)
+	if err != nil {
+		return 0, err
+	}
+
+	sender, ok := n.senders[channel.Type]
+	if !ok {
+		return 0, fmt.Errorf("неизвестный тип канала %s", channel.Type)
+	}
+
+	msg := msgs[0]
+	if len(msgs) > 1 {
+		if msg, err = n.groupMessage(channel, msgs); err != nil {
+			return 0, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	// Ошибка отправки сохраняется в журнале попыток: адрес с подставленным
+	// секретом в ней заменяется ссылкой
+	code, err := sender.Send(ctx, channel, msg)
+	return code, concealer.ConcealError(err)
+}
+
+func (n *Notifier) retryBase() time.Duration {
+	return time.Duration(n.config.NotifyRetryBase) * time.Second
+}
+
+func (n *Notifier) retryMax() time.Duration {
+	return time.Duration(n.config.NotifyRetryMax) * time.Second
+}
+
+// backoff задержка перед попыткой attempt+1: base, 2*base, 4*base, ... но не больше max
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,236 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// Templates шаблоны сообщений канала. Первая строка результата служит темой письма
+type Templates struct {
+	Firing   string
+	Resolved string
+	Group    string // несколько алертов, поставленных в очередь в течение окна группировки
+	Digest   string // периодическая сводка
+}
+
+// DefaultTemplates шаблоны по умолчанию для языков канала
+var DefaultTemplates = map[string]Templates{
+	"ru": {
+		Firing: `[{{upper .Alert.Severity}}] {{.Alert.Message}}
+{{if .Service}}Сервис: {{.Service.Name}} ({{.Service.URL}})
+{{end}}{{if .Check}}Последняя проверка: {{.Check.Status}}, {{.Check.ResponseTime}} мс{{if .Check.ErrorMessage}}, {{.Check.ErrorMessage}}{{end}} ({{.Check.Location}})
+{{end}}Начало: {{datetime .Alert.CreatedAt}}, длится {{duration .Duration}}
+Дашборд: {{.DashboardURL}}`,
+		Resolved: `[РЕШЕНО] {{.Alert.Message}}
+{{if .Service}}Сервис: {{.Service.Name}} ({{.Service.URL}})
+{{end}}{{if .Check}}Последняя проверка: {{.Check.Status}}, {{.Check.ResponseTime}} мс ({{.Check.Location}})
+{{end}}Алерт длился {{duration .Duration}}
+Дашборд: {{.DashboardURL}}`,
+		Group: `{{if eq .Event "resolved"}}[РЕШЕНО] Разрешено{{else}}[ГРУППА] Сработало{{end}} алертов: {{len .Alerts}}{{if .Services}} ({{join .Services ", "}}){{end}}
+{{range .Alerts}}- [{{upper .Alert.Severity}}] {{.Alert.Message}}{{if .Service}} ({{.Service}}){{end}}
+{{end}}Дашборд: {{.DashboardURL}}`,
+		Digest: `[СВОДКА] Открытых алертов: {{len .Open}}, разрешено с {{datetime .Since}}: {{len .Resolved}}
+{{if .Open}}Открыты:
+{{range .Open}}- [{{upper .Alert.Severity}}] {{.Alert.Message}}{{if .Service}} ({{.Service}}){{end}}, длится {{duration .Duration}}
+{{end}}{{end}}{{if .Resolved}}Разрешены:
+{{range .Resolved}}- {{.Alert.Message}}{{if .Service}} ({{.Service}}){{end}}, длился {{duration .Duration}}
+{{end}}{{end}}Дашборд: {{.DashboardURL}}`,
+	},
+	"en": {
+		Firing: `[{{upper .Alert.Severity}}] {{.Alert.Kind}} alert #{{.Alert.ID}}{{if .Service}} for {{.Service.Name}}{{end}}
+{{if .Service}}Service: {{.Service.Name}} ({{.Service.URL}})
+{{end}}{{if .Check}}Last check: {{.Check.Status}}, {{.Check.ResponseTime}} ms{{if .Check.ErrorMessage}}, {{.Check.ErrorMessage}}{{end}} ({{.Check.Location}})
+{{end}}Started: {{datetime .Alert.CreatedAt}}, firing for {{duration .Duration}}
+Dashboard: {{.DashboardURL}}`,
+		Resolved: `[RESOLVED] {{.Alert.Kind}} alert #{{.Alert.ID}}{{if .Service}} for {{.Service.Name}}{{end}}
+{{if .Service}}Service: {{.Service.Name}} ({{.Service.URL}})
+{{end}}{{if .Check}}Last check: {{.Check.Status}}, {{.Check.ResponseTime}} ms ({{.Check.Location}})
+{{end}}Resolved after {{duration .Duration}}
+Dashboard: {{.DashboardURL}}`,
+		Group: `{{if eq .Event "resolved"}}[RESOLVED]{{else}}[GROUP]{{end}} {{len .Alerts}} alerts {{.Event}}{{if .Services}} ({{join .Services ", "}}){{end}}
+{{range .Alerts}}- [{{upper .Alert.Severity}}] {{.Alert.Kind}} alert #{{.Alert.ID}}{{if .Service}} for {{.Service}}{{end}}
+{{end}}Dashboard: {{.DashboardURL}}`,
+		Digest: `[DIGEST] {{len .Open}} open alerts, {{len .Resolved}} resolved since {{datetime .Since}}
+{{if .Open}}Open:
+{{range .Open}}- [{{upper .Alert.Severity}}] {{.Alert.Kind}} alert #{{.Alert.ID}}{{if .Service}} for {{.Service}}{{end}}, firing for {{duration .Duration}}
+{{end}}{{end}}{{if .Resolved}}Resolved:
+{{range .Resolved}}- {{.Alert.Kind}} alert #{{.Alert.ID}}{{if .Service}} for {{.Service}}{{end}}, lasted {{duration .Duration}}
+{{end}}{{end}}Dashboard: {{.DashboardURL}}`,
+	},
+}
+
+// DefaultLanguage язык шаблонов канала, если он не указан
+const DefaultLanguage = "ru"
+
+// TemplateData данные, доступные в шаблоне
+type TemplateData struct {
+	Event        string
+	Alert        models.Alert
+	Service      *models.Service     // nil для алертов по группе сервисов
+	Check        *models.HealthCheck // последняя проверка сервиса
+	Duration     time.Duration       // сколько алерт активен или длился до разрешения
+	DashboardURL string
+}
+
+// AlertItem алерт в списке группового сообщения или сводки
+type AlertItem struct {
+	Alert    models.Alert
+	Service  string        // имя сервиса, пустое для алертов без сервиса
+	Duration time.Duration // сколько алерт активен или длился до разрешения
+}
+
+// GroupData данные шаблона группового сообщения
+type GroupData struct {
+	Event        string
+	Alerts       []AlertItem
+	Services     []string // затронутые сервисы без повторов
+	DashboardURL string
+}
+
+// DigestData данные шаблона сводки
+type DigestData struct {
+	Since        time.Time // время предыдущей сводки
+	Open         []AlertItem
+	Resolved     []AlertItem // разрешенные после Since
+	DashboardURL string
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+}
+
+// channelTemplates шаблоны канала с учетом языка и переопределений
+func channelTemplates(config models.ChannelConfig) (Templates, error) {
+	language := config.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	templates, ok := DefaultTemplates[language]
+	if !ok {
+		return Templates{}, fmt.Errorf("нет шаблонов для языка %s", language)
+	}
+
+	if config.FiringTemplate != "" {
+		templates.Firing = config.FiringTemplate
+	}
+	if config.ResolvedTemplate != "" {
+		templates.Resolved = config.ResolvedTemplate
+	}
+	if config.GroupTemplate != "" {
+		templates.Group = config.GroupTemplate
+	}
+	if config.DigestTemplate != "" {
+		templates.Digest = config.DigestTemplate
+	}
+	return templates, nil
+}
+
+// ValidateTemplates проверяет язык и синтаксис шаблонов канала
+func ValidateTemplates(config models.ChannelConfig) error {
+	templates, err := channelTemplates(config)
+	if err != nil {
+		return err
+	}
+
+	for name, text := range map[string]string{
+		"firing":   templates.Firing,
+		"resolved": templates.Resolved,
+		"group":    templates.Group,
+		"digest":   templates.Digest,
+	} {
+		if _, err := template.New(name).Funcs(templateFuncs).Parse(text); err != nil {
+			return fmt.Errorf("шаблон %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Render формирует сообщение для канала по его шаблону события
+func Render(channel models.Channel, data TemplateData) (Message, error) {
+	templates, err := channelTemplates(channel.Config)
+	if err != nil {
+		return Message{}, err
+	}
+
+	text := templates.Firing
+	if data.Event == EventResolved {
+		text = templates.Resolved
+	}
+
+	msg, err := render(data.Event, text, data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Event = data.Event
+	msg.Alert = data.Alert
+	return msg, nil
+}
+
+// RenderGroup формирует одно сообщение о нескольких алертах
+func RenderGroup(channel models.Channel, data GroupData) (Message, error) {
+	templates, err := channelTemplates(channel.Config)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg, err := render("group", templates.Group, data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Event = data.Event
+	for _, item := range data.Alerts {
+		msg.Alerts = append(msg.Alerts, item.Alert)
+	}
+	return msg, nil
+}
+
+// RenderDigest формирует сводку по алертам канала
+func RenderDigest(channel models.Channel, data DigestData) (Message, error) {
+	templates, err := channelTemplates(channel.Config)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg, err := render("digest", templates.Digest, data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Event = EventDigest
+	for _, item := range append(data.Open, data.Resolved...) {
+		msg.Alerts = append(msg.Alerts, item.Alert)
+	}
+	return msg, nil
+}
+
+// render выполняет шаблон; первая строка результата становится темой
+func render(name, text string, data interface{}) (Message, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return Message{}, fmt.Errorf("шаблон %s: %w", name, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return Message{}, fmt.Errorf("шаблон %s: %w", name, err)
+	}
+
+	rendered := strings.TrimSpace(out.String())
+	subject, _, _ := strings.Cut(rendered, "\n")
+
+	return Message{
+		Subject: subject,
+		Text:    rendered,
+	}, nil
+}
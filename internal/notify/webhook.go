@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// webhookTimeout ограничение на один запрос к webhook
+const webhookTimeout = 10 * time.Second
+
+// webhookSender отправляет оповещение POST запросом с JSON телом
+type webhookSender struct {
+	client *http.Client
+}
+
+func newWebhookSender() *webhookSender {
+	return &webhookSender{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (w *webhookSender) Send(ctx context.Context, channel models.Channel, msg Message) (int, error) {
+	if channel.Config.URL == "" {
+		return 0, fmt.Errorf("не указан url")
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range channel.Config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook ответил HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,211 @@
+package oncall
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+	"service-monitor/internal/notify"
+)
+
+// escalateInterval период проверки алертов, ожидающих оповещения
+const escalateInterval = 30 * time.Second
+
+// Escalator оповещает уровни политики эскалации сервиса, пока алерт
+// не подтвержден, и сообщает оповещенным уровням о разрешении алерта.
+// Оповещения ставятся в outbox, доставляет их Notifier.Run
+type Escalator struct {
+	db       *database.DB
+	resolver *Resolver
+	notifier *notify.Notifier
+	logger   *logger.Logger
+}
+
+func NewEscalator(db *database.DB, resolver *Resolver, notifier *notify.Notifier, logger *logger.Logger) *Escalator {
+	return &Escalator{
+		db:       db,
+		resolver: resolver,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// escalation алерт вместе с политикой его сервиса
+type escalation struct {
+	alert       models.Alert
+	escalatedAt *time.Time
+	levels      models.EscalationLevels
+}
+
+// Run проверяет алерты каждые 30 секунд до отмены ctx
+func (e *Escalator) Run(ctx context.Context) {
+	ticker := time.NewTicker(escalateInterval)
+	defer ticker.Stop()
+
+	for {
+		e.escalate()
+		e.notifyResolved()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// escalate оповещает следующий уровень для неподтвержденных активных алертов
+func (e *Escalator) escalate() {
+	pending, err := e.pending(`a.is_resolved = false AND a.acknowledged_at IS NULL`)
+	if err != nil {
+		e.logger.Error("Ошибка получения алертов для эскалации:", err)
+		return
+	}
+
+	now := time.Now()
+	for _, p := range pending {
+		level := nextLevel(p.levels, p.alert.EscalationLevel, p.escalatedAt, now)
+		if level == 0 {
+			continue
+		}
+
+		// Уровень засчитывается один раз, даже если проверка идет параллельно
+		result, err := e.db.Exec(`
+			UPDATE alerts SET escalation_level = $2, escalated_at = $3
+			WHERE id = $1 AND escalation_level = $4
+		`, p.alert.ID, level, now, p.alert.EscalationLevel)
+		if err != nil {
+			e.logger.Error("Ошибка обновления уровня эскалации:", err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+
+		p.alert.EscalationLevel = level
+		e.notifyLevel(p.levels[level-1], notify.EventFiring, p.alert)
+	}
+}
+
+// notifyResolved сообщает о разрешении всем уровням, которые были оповещены
+func (e *Escalator) notifyResolved() {
+	pending, err := e.pending(`a.is_resolved = true AND a.escalation_level > 0 AND a.resolve_notified = false`)
+	if err != nil {
+		e.logger.Error("Ошибка получения разрешенных алертов:", err)
+		return
+	}
+
+	for _, p := range pending {
+		if _, err := e.db.Exec(`UPDATE alerts SET resolve_notified = true WHERE id = $1`, p.alert.ID); err != nil {
+			e.logger.Error("Ошибка обновления алерта:", err)
+			continue
+		}
+
+		for i := 0; i < p.alert.EscalationLevel && i < len(p.levels); i++ {
+			e.notifyLevel(p.levels[i], notify.EventResolved, p.alert)
+		}
+	}
+}
+
+func (e *Escalator) pending(condition string) ([]escalation, error) {
+	query := `
+		SELECT a.id, a.service_id, a.message, a.severity, a.kind, a.fingerprint, a.is_resolved,
+		       a.created_at, a.resolved_at, a.escalation_level, a.escalated_at, p.levels
+		FROM alerts a
+		JOIN services s ON s.id = a.service_id
+		JOIN escalation_policies p ON p.id = s.escalation_policy_id
+		WHERE ` + condition + ` AND ` + alerting.ByEscalation + `
+		ORDER BY a.id
+	`
+
+	rows, err := e.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []escalation
+	for rows.Next() {
+		var p escalation
+		a := &p.alert
+		err := rows.Scan(&a.ID, &a.ServiceID, &a.Message, &a.Severity, &a.Kind, &a.Fingerprint, &a.IsResolved,
+			&a.CreatedAt, &a.ResolvedAt, &a.EscalationLevel, &p.escalatedAt, &p.levels)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+// notifyLevel ставит в очередь оповещение цели уровня; для графика это тот,
+// кто дежурит в момент постановки
+func (e *Escalator) notifyLevel(level models.EscalationLevel, event string, alert models.Alert) {
+	channelID, recipient, err := e.channelFor(level)
+	if err != nil {
+		e.logger.Errorf("Ошибка оповещения по алерту %d: %v", alert.ID, err)
+		return
+	}
+
+	if err := e.notifier.Enqueue(channelID, recipient, event, alert); err != nil {
+		e.logger.Errorf("Ошибка оповещения по алерту %d: %v", alert.ID, err)
+	}
+}
+
+// channelFor возвращает канал цели уровня и имя получателя для журнала
+func (e *Escalator) channelFor(level models.EscalationLevel) (int, string, error) {
+	switch {
+	case level.ChannelID != nil:
+		var name string
+		err := e.db.QueryRow(`SELECT name FROM channels WHERE id = $1`, *level.ChannelID).Scan(&name)
+		if err != nil {
+			return 0, "", fmt.Errorf("канал %d: %w", *level.ChannelID, err)
+		}
+		return *level.ChannelID, name, nil
+	case level.ResponderID != nil:
+		var channelID int
+		var name string
+		err := e.db.QueryRow(`SELECT channel_id, name FROM responders WHERE id = $1`, *level.ResponderID).
+			Scan(&channelID, &name)
+		if err != nil {
+			return 0, "", fmt.Errorf("дежурный %d: %w", *level.ResponderID, err)
+		}
+		return channelID, name, nil
+	case level.ScheduleID != nil:
+		onCall, err := e.resolver.OnCall(*level.ScheduleID, time.Now())
+		if err != nil {
+			return 0, "", fmt.Errorf("график %d: %w", *level.ScheduleID, err)
+		}
+		if onCall.Responder == nil {
+			return 0, "", fmt.Errorf("по графику %s никто не дежурит", onCall.ScheduleName)
+		}
+		return onCall.Responder.ChannelID, onCall.Responder.Name, nil
+	}
+	return 0, "", fmt.Errorf("у уровня эскалации не указана цель")
+}
+
+// nextLevel возвращает номер уровня (с 1), который пора оповестить, или 0.
+// Первый уровень оповещается сразу, следующий - когда истек таймаут
+// предыдущего; уровень с нулевым таймаутом не эскалируется дальше
+func nextLevel(levels models.EscalationLevels, current int, notifiedAt *time.Time, now time.Time) int {
+	if len(levels) == 0 {
+		return 0
+	}
+	if current == 0 {
+		return 1
+	}
+	if current >= len(levels) || notifiedAt == nil {
+		return 0
+	}
+
+	timeout := time.Duration(levels[current-1].TimeoutMinutes) * time.Minute
+	if timeout <= 0 || now.Before(notifiedAt.Add(timeout)) {
+		return 0
+	}
+	return current + 1
+}
@@ -0,0 +1,150 @@
+package oncall
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+)
+
+// ErrNoSchedule график не найден
+var ErrNoSchedule = errors.New("график не найден")
+
+// ScheduleColumns список колонок schedules в порядке, который ожидает ScanSchedule
+const ScheduleColumns = `id, name, rotation, starts_at, participants, created_at`
+
+// ScanSchedule читает строку, выбранную через ScheduleColumns
+func ScanSchedule(row database.Scanner, schedule *models.Schedule) error {
+	var participants []int64
+	err := row.Scan(&schedule.ID, &schedule.Name, &schedule.Rotation, &schedule.StartsAt,
+		pq.Array(&participants), &schedule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	schedule.Participants = make([]int, len(participants))
+	for i, id := range participants {
+		schedule.Participants[i] = int(id)
+	}
+	return nil
+}
+
+// Resolver определяет текущих дежурных по графикам из БД
+type Resolver struct {
+	db *database.DB
+}
+
+func NewResolver(db *database.DB) *Resolver {
+	return &Resolver{db: db}
+}
+
+// OnCall кто дежурит по графику в момент at
+func (r *Resolver) OnCall(scheduleID int, at time.Time) (*models.OnCall, error) {
+	var schedule models.Schedule
+	err := ScanSchedule(r.db.QueryRow(`SELECT `+ScheduleColumns+` FROM schedules WHERE id = $1`, scheduleID), &schedule)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoSchedule
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := r.Overrides(scheduleID, at, at.Add(time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	responderID, override, until := Resolve(schedule, overrides, at)
+
+	result := &models.OnCall{
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		Override:     override,
+		Until:        until,
+	}
+
+	if responderID != 0 {
+		var responder models.Responder
+		err := r.db.QueryRow(`SELECT id, name, channel_id, created_at FROM responders WHERE id = $1`, responderID).
+			Scan(&responder.ID, &responder.Name, &responder.ChannelID, &responder.CreatedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			result.Responder = &responder
+		}
+	}
+
+	return result, nil
+}
+
+// Overrides подмены графика, пересекающиеся с [from, to)
+func (r *Resolver) Overrides(scheduleID int, from, to time.Time) ([]models.ScheduleOverride, error) {
+	query := `
+		SELECT id, schedule_id, responder_id, starts_at, ends_at, created_at
+		FROM schedule_overrides
+		WHERE schedule_id = $1 AND starts_at < $3 AND ends_at > $2
+		ORDER BY starts_at
+	`
+
+	rows, err := r.db.Query(query, scheduleID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []models.ScheduleOverride{}
+	for rows.Next() {
+		var o models.ScheduleOverride
+		if err := rows.Scan(&o.ID, &o.ScheduleID, &o.ResponderID, &o.StartsAt, &o.EndsAt, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	return overrides, rows.Err()
+}
+
+// Resolve возвращает ID дежурного в момент at, признак подмены и время,
+// до которого он дежурит. Из нескольких подмен действует последняя созданная
+func Resolve(schedule models.Schedule, overrides []models.ScheduleOverride, at time.Time) (int, bool, time.Time) {
+	var active *models.ScheduleOverride
+	for i := range overrides {
+		o := &overrides[i]
+		if at.Before(o.StartsAt) || !at.Before(o.EndsAt) {
+			continue
+		}
+		if active == nil || o.ID > active.ID {
+			active = o
+		}
+	}
+	if active != nil {
+		return active.ResponderID, true, active.EndsAt
+	}
+
+	if len(schedule.Participants) == 0 {
+		return 0, false, time.Time{}
+	}
+
+	period := 24 * time.Hour
+	if schedule.Rotation == models.RotationWeekly {
+		period = 7 * 24 * time.Hour
+	}
+
+	// Номер смены от начала графика; до начала смены идут в обратном порядке
+	elapsed := at.Sub(schedule.StartsAt)
+	shift := int64(elapsed / period)
+	if elapsed < 0 && elapsed%period != 0 {
+		shift--
+	}
+
+	n := int64(len(schedule.Participants))
+	index := (shift%n + n) % n
+	until := schedule.StartsAt.Add(time.Duration(shift+1) * period)
+
+	return schedule.Participants[index], false, until
+}
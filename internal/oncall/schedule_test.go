@@ -0,0 +1,100 @@
+package oncall
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/models"
+)
+
+var start = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func TestResolveDailyRotation(t *testing.T) {
+	schedule := models.Schedule{Rotation: models.RotationDaily, StartsAt: start, Participants: []int{1, 2, 3}}
+
+	id, override, until := Resolve(schedule, nil, start)
+	assert.Equal(t, 1, id)
+	assert.False(t, override)
+	assert.Equal(t, start.Add(24*time.Hour), until)
+
+	id, _, _ = Resolve(schedule, nil, start.Add(24*time.Hour-time.Second))
+	assert.Equal(t, 1, id)
+
+	id, _, _ = Resolve(schedule, nil, start.Add(24*time.Hour))
+	assert.Equal(t, 2, id)
+
+	id, _, until = Resolve(schedule, nil, start.Add(3*24*time.Hour+time.Hour))
+	assert.Equal(t, 1, id)
+	assert.Equal(t, start.Add(4*24*time.Hour), until)
+}
+
+func TestResolveWeeklyRotation(t *testing.T) {
+	schedule := models.Schedule{Rotation: models.RotationWeekly, StartsAt: start, Participants: []int{1, 2}}
+
+	id, _, _ := Resolve(schedule, nil, start.Add(6*24*time.Hour))
+	assert.Equal(t, 1, id)
+
+	id, _, until := Resolve(schedule, nil, start.Add(8*24*time.Hour))
+	assert.Equal(t, 2, id)
+	assert.Equal(t, start.Add(14*24*time.Hour), until)
+}
+
+func TestResolveBeforeStart(t *testing.T) {
+	schedule := models.Schedule{Rotation: models.RotationDaily, StartsAt: start, Participants: []int{1, 2, 3}}
+
+	id, _, until := Resolve(schedule, nil, start.Add(-time.Hour))
+	assert.Equal(t, 3, id)
+	assert.Equal(t, start, until)
+
+	id, _, _ = Resolve(schedule, nil, start.Add(-24*time.Hour))
+	assert.Equal(t, 3, id)
+}
+
+func TestResolveOverride(t *testing.T) {
+	schedule := models.Schedule{Rotation: models.RotationDaily, StartsAt: start, Participants: []int{1, 2}}
+	overrides := []models.ScheduleOverride{
+		{ID: 1, ResponderID: 7, StartsAt: start.Add(time.Hour), EndsAt: start.Add(5 * time.Hour)},
+		{ID: 2, ResponderID: 8, StartsAt: start.Add(2 * time.Hour), EndsAt: start.Add(3 * time.Hour)},
+	}
+
+	id, override, until := Resolve(schedule, overrides, start.Add(90*time.Minute))
+	assert.Equal(t, 7, id)
+	assert.True(t, override)
+	assert.Equal(t, start.Add(5*time.Hour), until)
+
+	// Более поздняя подмена перекрывает раннюю
+	id, _, _ = Resolve(schedule, overrides, start.Add(150*time.Minute))
+	assert.Equal(t, 8, id)
+
+	// Конец подмены не включается
+	id, override, _ = Resolve(schedule, overrides, start.Add(5*time.Hour))
+	assert.Equal(t, 1, id)
+	assert.False(t, override)
+}
+
+func TestResolveEmptySchedule(t *testing.T) {
+	id, _, _ := Resolve(models.Schedule{Rotation: models.RotationDaily, StartsAt: start}, nil, start)
+	assert.Equal(t, 0, id)
+}
+
+func TestNextLevel(t *testing.T) {
+	now := start
+	levels := models.EscalationLevels{{TimeoutMinutes: 10}, {TimeoutMinutes: 0}}
+
+	// Первый уровень оповещается сразу
+	assert.Equal(t, 1, nextLevel(levels, 0, nil, now))
+
+	notified := now.Add(-5 * time.Minute)
+	assert.Equal(t, 0, nextLevel(levels, 1, &notified, now))
+
+	notified = now.Add(-10 * time.Minute)
+	assert.Equal(t, 2, nextLevel(levels, 1, &notified, now))
+
+	// Последний уровень дальше не эскалируется
+	notified = now.Add(-time.Hour)
+	assert.Equal(t, 0, nextLevel(levels, 2, &notified, now))
+
+	assert.Equal(t, 0, nextLevel(nil, 0, nil, now))
+}
@@ -0,0 +1,219 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	// tokenRefreshMargin токен обновляется заранее, чтобы не истечь во время проверки
+	tokenRefreshMargin = 30 * time.Second
+	// defaultTokenTTL срок кэширования токена без expires_in в ответе
+	defaultTokenTTL = 5 * time.Minute
+)
+
+// clientTLS собирает tls.Config из настроек сервиса
+func clientTLS(cfg models.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cfg.CACert)) {
+			return nil, errors.New("ca_cert: не найдено ни одного сертификата PEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(cfg.ClientCert), []byte(cfg.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("клиентский сертификат: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// authorize добавляет к запросу заголовок аутентификации
+func authorize(ctx context.Context, client *http.Client, auth *models.AuthConfig, req *http.Request) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch auth.Type {
+	case models.AuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case models.AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case models.AuthOAuth2:
+		token, err := oauthTokens.get(ctx, client, *auth.OAuth2)
+		if err != nil {
+			return fmt.Errorf("OAuth2: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// rejectAuth сбрасывает закэшированный токен, если сервис его не принял,
+// чтобы следующая проверка получила новый
+func rejectAuth(auth *models.AuthConfig, code int) {
+	if auth != nil && auth.Type == models.AuthOAuth2 && code == http.StatusUnauthorized {
+		oauthTokens.forget(*auth.OAuth2)
+	}
+}
+
+// oauthToken закэшированный токен; mu держится на время получения, чтобы
+// параллельные проверки одного клиента не запрашивали токен одновременно
+type oauthToken struct {
+	mu        sync.Mutex
+	value     string
+	expiresAt time.Time
+}
+
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*oauthToken
+}
+
+var oauthTokens = &tokenCache{tokens: make(map[string]*oauthToken)}
+
+func tokenKey(cfg models.OAuth2Config) string {
+	sum := sha256.Sum256([]byte(cfg.TokenURL + "\x00" + cfg.ClientID + "\x00" + cfg.ClientSecret + "\x00" +
+		strings.Join(cfg.Scopes, " ")))
+	return string(sum[:])
+}
+
+func (c *tokenCache) entry(cfg models.OAuth2Config) *oauthToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := tokenKey(cfg)
+	token, ok := c.tokens[key]
+	if !ok {
+		token = &oauthToken{}
+		c.tokens[key] = token
+	}
+	return token
+}
+
+// get возвращает действующий токен, при необходимости получая новый
+func (c *tokenCache) get(ctx context.Context, client *http.Client, cfg models.OAuth2Config) (string, error) {
+	token := c.entry(cfg)
+
+	token.mu.Lock()
+	defer token.mu.Unlock()
+
+	if token.value != "" && time.Until(token.expiresAt) > tokenRefreshMargin {
+		return token.value, nil
+	}
+
+	value, ttl, err := fetchToken(ctx, client, cfg)
+	if err != nil {
+		return "", err
+	}
+	token.value = value
+	token.expiresAt = time.Now().Add(ttl)
+	return value, nil
+}
+
+func (c *tokenCache) forget(cfg models.OAuth2Config) {
+	token := c.entry(cfg)
+
+	token.mu.Lock()
+	defer token.mu.Unlock()
+	token.value = ""
+}
+
+// fetchToken запрашивает токен по client credentials (RFC 6749, 4.4)
+func fetchToken(ctx context.Context, client *http.Client, cfg models.OAuth2Config) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("сервер токенов вернул HTTP %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("ответ сервера токенов: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, errors.New("в ответе сервера токенов нет access_token")
+	}
+
+	ttl := defaultTokenTTL
+	if token.ExpiresIn > 0 {
+		ttl = time.Duration(token.ExpiresIn) * time.Second
+	}
+	return token.AccessToken, ttl, nil
+}
+
+// validateAuth проверяет настройки аутентификации и TLS
+func validateAuth(cfg models.ProbeConfig) error {
+	if auth := cfg.Auth; auth != nil {
+		switch auth.Type {
+		case models.AuthBasic:
+			if auth.Username == "" {
+				return errors.New("auth: для basic нужен username")
+			}
+		case models.AuthBearer:
+			if auth.Token == "" {
+				return errors.New("auth: для bearer нужен token")
+			}
+		case models.AuthOAuth2:
+			if auth.OAuth2 == nil || auth.OAuth2.TokenURL == "" || auth.OAuth2.ClientID == "" {
+				return errors.New("auth: для oauth2 нужны token_url и client_id")
+			}
+			if _, err := url.ParseRequestURI(auth.OAuth2.TokenURL); err != nil {
+				return fmt.Errorf("auth: token_url: %w", err)
+			}
+		default:
+			return fmt.Errorf("auth: неизвестный тип %s", auth.Type)
+		}
+	}
+
+	if cfg.TLS != nil {
+		if _, err := clientTLS(*cfg.TLS); err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package probe
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+)
+
+// defaultQuery запрос проверки Postgres и MySQL по умолчанию
+const defaultQuery = "SELECT 1"
+
+// DatabaseResult подробности проверки базы данных; время в миллисекундах
+type DatabaseResult struct {
+	ConnectTime float64 `json:"connect_time"` // подключение и аутентификация
+	QueryTime   float64 `json:"query_time"`
+	Value       string  `json:"value"`
+	// ReplicationLag отставание реплики в секундах, если оно проверялось
+	ReplicationLag *float64 `json:"replication_lag,omitempty"`
+}
+
+func databaseConfig(cfg models.ProbeConfig) models.DatabaseProbeConfig {
+	if cfg.Database == nil {
+		return models.DatabaseProbeConfig{}
+	}
+	return *cfg.Database
+}
+
+// runSQL подключается к Postgres или MySQL, выполняет запрос и проверяет
+// результат и отставание реплики. Время ответа - время выполнения запроса
+func runSQL(ctx context.Context, service models.Service, driver string) Result {
+	cfg := databaseConfig(service.ProbeConfig)
+	result := Result{CheckedAt: time.Now()}
+
+	db, err := openDatabase(service, driver)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	defer db.Close()
+
+	start := time.Now()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		result.ResponseTime = int(time.Since(start).Milliseconds())
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	defer conn.Close()
+	connected := time.Now()
+
+	query := cfg.Query
+	if query == "" {
+		query = defaultQuery
+	}
+	value, err := queryScalar(ctx, conn, query)
+	queried := time.Now()
+
+	result.ResponseTime = int(queried.Sub(connected).Milliseconds())
+	result.Database = &DatabaseResult{
+		ConnectTime: millis(start, connected),
+		QueryTime:   millis(connected, queried),
+		Value:       value,
+	}
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	if cfg.Expected != "" {
+		result.Assertions = append(result.Assertions, valueAssertion(value, cfg.Expected))
+	}
+
+	if cfg.MaxReplicationLagSeconds > 0 {
+		lagQuery := postgresLag
+		if driver == models.ProbeMySQL {
+			lagQuery = mysqlLag
+		}
+		lag, err := lagQuery(ctx, conn)
+		if err != nil {
+			result.Status = models.StatusUnhealthy
+			result.ErrorMessage = "отставание реплики: " + err.Error()
+			return result
+		}
+		result.Database.ReplicationLag = &lag
+		result.Assertions = append(result.Assertions, lagAssertion(lag, cfg.MaxReplicationLagSeconds))
+	}
+
+	result.finish()
+	return result
+}
+
+// mysqlNetwork сеть драйвера MySQL, соединения которой идут через
+// соединитель проверки из контекста подключения
+const mysqlNetwork = "probe"
+
+type dialerKey struct{}
+
+func init() {
+	mysql.RegisterDialContext(mysqlNetwork, func(ctx context.Context, address string) (net.Conn, error) {
+		return ctx.Value(dialerKey{}).(*dialer).DialContext(ctx, "tcp", address)
+	})
+}
+
+// openDatabase открывает базу с соединителем, учитывающим сетевые настройки сервиса
+func openDatabase(service models.Service, driver string) (*sql.DB, error) {
+	d, err := probeDialer(service.ProbeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if driver == models.ProbePostgres {
+		connector, err := pq.NewConnector(service.URL)
+		if err != nil {
+			return nil, err
+		}
+		connector.Dialer(d)
+		return sql.OpenDB(connector), nil
+	}
+
+	cfg, err := mysqlConfig(service.URL)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(mysqlConnector{Connector: connector, dialer: d}), nil
+}
+
+// mysqlConfig разбирает DSN MySQL; TCP соединения идут через соединитель
+// проверки, unix-сокеты - напрямую
+func mysqlConfig(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Net == "tcp" {
+		cfg.Net = mysqlNetwork
+	}
+	return cfg, nil
+}
+
+// mysqlConnector передает соединитель проверки драйверу MySQL через контекст
+type mysqlConnector struct {
+	driver.Connector
+	dialer *dialer
+}
+
+func (c mysqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.Connector.Connect(context.WithValue(ctx, dialerKey{}, c.dialer))
+}
+
+// queryScalar возвращает первую колонку первой строки результата
+func queryScalar(ctx context.Context, conn *sql.Conn, query string) (string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("запрос не вернул строк")
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	return values[0].String, rows.Err()
+}
+
+// postgresLag отставание реплики Postgres; на основном сервере 0
+func postgresLag(ctx context.Context, conn *sql.Conn) (float64, error) {
+	query := `
+		SELECT CASE WHEN pg_is_in_recovery()
+		            THEN COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		            ELSE 0 END
+	`
+
+	var lag float64
+	err := conn.QueryRowContext(ctx, query).Scan(&lag)
+	return lag, err
+}
+
+// mysqlLag отставание реплики MySQL по Seconds_Behind_Source; не реплика - 0
+func mysqlLag(ctx context.Context, conn *sql.Conn) (float64, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// MySQL до 8.0.22 знает только старое название команды
+		rows, err = conn.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	return replicaLag(columns, values)
+}
+
+// replicaLag находит отставание в строке SHOW REPLICA STATUS; NULL означает,
+// что репликация остановлена
+func replicaLag(columns []string, values []sql.NullString) (float64, error) {
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("репликация остановлена")
+		}
+		return strconv.ParseFloat(values[i].String, 64)
+	}
+	return 0, errors.New("нет колонки Seconds_Behind_Source")
+}
+
+// valueAssertion сравнивает значение первой колонки с ожидаемым как строки
+func valueAssertion(value, expected string) AssertionResult {
+	return AssertionResult{Name: "result", Expected: expected, Actual: value, Passed: value == expected}
+}
+
+func lagAssertion(lag, max float64) AssertionResult {
+	return AssertionResult{
+		Name:     "replication_lag",
+		Expected: fmt.Sprintf("<= %g с", max),
+		Actual:   fmt.Sprintf("%g с", lag),
+		Passed:   lag <= max,
+	}
+}
+
+// validateDatabase проверяет, что настройки подходят типу проверки
+func validateDatabase(cfg models.ProbeConfig) error {
+	c := databaseConfig(cfg)
+	if c.MaxReplicationLagSeconds < 0 {
+		return errors.New("max_replication_lag_seconds не может быть отрицательным")
+	}
+	if cfg.Type == models.ProbeRedis && c.Query != "" {
+		return errors.New("для Redis задается command, а не query")
+	}
+	if cfg.Type != models.ProbeRedis && c.Command != "" {
+		return errors.New("command задается только для Redis")
+	}
+	return nil
+}
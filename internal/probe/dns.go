@@ -0,0 +1,190 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// dnsPort порт DNS сервера, если в адресе резолвера он не указан
+const dnsPort = "53"
+
+func runDNS(ctx context.Context, service models.Service) Result {
+	cfg := dnsConfig(service.ProbeConfig)
+	result := Result{CheckedAt: time.Now()}
+
+	start := time.Now()
+	answers, err := lookup(ctx, newResolver(cfg.Resolver), cfg.RecordType, service.URL)
+	result.ResponseTime = int(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	result.Answers = answers
+
+	if len(cfg.Expected) > 0 {
+		expected := normalizeAnswers(cfg.RecordType, cfg.Expected)
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Name:     "answers",
+			Expected: strings.Join(expected, ", "),
+			Actual:   strings.Join(answers, ", "),
+			Passed:   equalAnswers(expected, answers),
+		})
+	}
+
+	result.finish()
+	return result
+}
+
+// dnsConfig настройки DNS проверки со значениями по умолчанию
+func dnsConfig(cfg models.ProbeConfig) models.DNSProbeConfig {
+	var dns models.DNSProbeConfig
+	if cfg.DNS != nil {
+		dns = *cfg.DNS
+	}
+	dns.RecordType = strings.ToUpper(dns.RecordType)
+	if dns.RecordType == "" {
+		dns.RecordType = models.RecordA
+	}
+	return dns
+}
+
+// newResolver системный резолвер или резолвер, обращающийся к указанному серверу
+func newResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, dnsPort)
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// lookup запрашивает записи указанного типа и возвращает их в
+// нормализованном виде, отсортированными
+func lookup(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case models.RecordA, models.RecordAAAA:
+		network := "ip4"
+		if recordType == models.RecordAAAA {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case models.RecordCNAME:
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case models.RecordMX:
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case models.RecordTXT:
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	case models.RecordSRV:
+		_, records, err := resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range records {
+			answers = append(answers, fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, srv.Target))
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип записи %s", recordType)
+	}
+
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("нет записей %s для %s", recordType, name)
+	}
+	return normalizeAnswers(recordType, answers), nil
+}
+
+// normalizeAnswers приводит ответы к виду для сравнения: имена в нижнем
+// регистре без завершающей точки, IP в каноническом виде, по порядку
+func normalizeAnswers(recordType string, answers []string) []string {
+	normalized := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		answer = strings.TrimSpace(answer)
+		switch recordType {
+		case models.RecordTXT:
+		case models.RecordA, models.RecordAAAA:
+			if ip := net.ParseIP(answer); ip != nil {
+				answer = ip.String()
+			}
+		default:
+			answer = strings.TrimSuffix(strings.ToLower(answer), ".")
+		}
+		normalized = append(normalized, answer)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// equalAnswers сравнивает отсортированные наборы ответов
+func equalAnswers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateDNS проверяет тип записи и адрес резолвера
+func validateDNS(cfg models.ProbeConfig) error {
+	dns := dnsConfig(cfg)
+	switch dns.RecordType {
+	case models.RecordA, models.RecordAAAA, models.RecordCNAME, models.RecordMX, models.RecordTXT, models.RecordSRV:
+	default:
+		return fmt.Errorf("неподдерживаемый тип записи %s", dns.RecordType)
+	}
+
+	if dns.Resolver != "" {
+		return validateResolver(dns.Resolver)
+	}
+	return nil
+}
+
+// validateResolver проверяет адрес DNS сервера host[:port]
+func validateResolver(address string) error {
+	if _, port, err := net.SplitHostPort(address); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return fmt.Errorf("неверный адрес резолвера %s", address)
+		}
+	}
+	return nil
+}
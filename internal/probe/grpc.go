@@ -0,0 +1,115 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"service-monitor/internal/models"
+)
+
+// runGRPC вызывает grpc.health.v1.Health/Check. SERVING - сервис работает,
+// UNKNOWN - деградация (сервер еще не определил состояние), остальное - недоступен
+func runGRPC(ctx context.Context, service models.Service) Result {
+	var cfg models.GRPCProbeConfig
+	if service.ProbeConfig.GRPC != nil {
+		cfg = *service.ProbeConfig.GRPC
+	}
+	result := Result{CheckedAt: time.Now()}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	d, err := probeDialer(service.ProbeConfig)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", address)
+	}
+
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, service.URL, grpc.WithTransportCredentials(creds), grpc.WithContextDialer(dial))
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	if len(cfg.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(cfg.Metadata))
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: cfg.Service})
+	result.ResponseTime = int(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = grpcError(err, cfg.Service)
+		return result
+	}
+
+	serving := resp.GetStatus().String()
+	result.Assertions = append(result.Assertions, AssertionResult{
+		Name:     "grpc_status",
+		Expected: healthpb.HealthCheckResponse_SERVING.String(),
+		Actual:   serving,
+		Passed:   resp.GetStatus() == healthpb.HealthCheckResponse_SERVING,
+	})
+
+	result.finish()
+	if resp.GetStatus() == healthpb.HealthCheckResponse_UNKNOWN {
+		result.Status = models.StatusDegraded
+	}
+	if result.Status != models.StatusHealthy {
+		result.ErrorMessage = "gRPC health: " + serving
+	}
+	return result
+}
+
+// grpcError понятное описание ошибки вызова Check
+func grpcError(err error, service string) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err.Error()
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		return fmt.Sprintf("сервис %q не зарегистрирован в gRPC health", service)
+	case codes.Unimplemented:
+		return "сервер не реализует grpc.health.v1.Health"
+	case codes.DeadlineExceeded:
+		return "таймаут gRPC health проверки"
+	}
+	return fmt.Sprintf("gRPC %s: %s", st.Code(), st.Message())
+}
+
+// validateGRPC проверяет имена метаданных: служебные заголовки gRPC задавать нельзя
+func validateGRPC(cfg models.ProbeConfig) error {
+	if cfg.GRPC == nil {
+		return nil
+	}
+	for key := range cfg.GRPC.Metadata {
+		key = strings.ToLower(key)
+		if key == "" || strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") {
+			return fmt.Errorf("недопустимое имя метаданных %q", key)
+		}
+	}
+	return nil
+}
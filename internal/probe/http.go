@@ -0,0 +1,127 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// maxBodySize ограничение на читаемое тело ответа
+const maxBodySize = 1 << 20
+
+func runHTTP(ctx context.Context, service models.Service) Result {
+	cfg := service.ProbeConfig
+	result := Result{CheckedAt: time.Now()}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, service.URL, nil)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client, err := httpClient(cfg)
+	if err == nil {
+		err = authorize(ctx, client, cfg.Auth, req)
+	}
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	resp, body, timings, err := doHTTP(client, req)
+	result.ResponseTime = int(timings.elapsed.Milliseconds())
+	result.Timings = timings.phases
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	rejectAuth(cfg.Auth, resp.StatusCode)
+
+	result.StatusCode = resp.StatusCode
+	result.Headers = make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		result.Headers[name] = resp.Header.Get(name)
+	}
+
+	result.Assertions = append(result.Assertions, checkStatus(cfg.ExpectedStatus, resp.StatusCode))
+	if cfg.BodyContains != "" {
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Name:     "body_contains",
+			Expected: cfg.BodyContains,
+			Actual:   fmt.Sprintf("%d байт", len(body)),
+			Passed:   strings.Contains(string(body), cfg.BodyContains),
+		})
+	}
+
+	result.finish()
+	if result.Status == models.StatusUnhealthy && !result.Assertions[0].Passed {
+		result.ErrorMessage = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+
+	return result
+}
+
+// requestTimings общее время запроса и разбивка по фазам
+type requestTimings struct {
+	elapsed time.Duration
+	phases  *models.Timings
+}
+
+// doHTTP выполняет запрос и читает тело ответа не больше maxBodySize,
+// замеряя фазы запроса. Тело ответа уже закрыто
+func doHTTP(client *http.Client, req *http.Request) (*http.Response, []byte, requestTimings, error) {
+	start := time.Now()
+
+	tracer := &timingTracer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.clientTrace()))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		end := time.Now()
+		return nil, nil, requestTimings{elapsed: end.Sub(start), phases: tracer.timings(end)}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	end := time.Now()
+	return resp, body, requestTimings{elapsed: end.Sub(start), phases: tracer.timings(end)}, err
+}
+
+// checkStatus проверяет код ответа; без явного списка ожидается 2xx
+func checkStatus(expected []int, code int) AssertionResult {
+	a := AssertionResult{Name: "status_code", Actual: strconv.Itoa(code)}
+
+	if len(expected) == 0 {
+		a.Expected = "2xx"
+		a.Passed = code >= 200 && code < 300
+		return a
+	}
+
+	codes := make([]string, len(expected))
+	for i, c := range expected {
+		codes[i] = strconv.Itoa(c)
+		if c == code {
+			a.Passed = true
+		}
+	}
+	a.Expected = strings.Join(codes, ",")
+	return a
+}
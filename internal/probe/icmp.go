@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	// pingCount и pingInterval значения по умолчанию для ping проверки
+	pingCount    = 3
+	pingInterval = 200 * time.Millisecond
+	// pingTimeout сколько ждать ответа на один эхо-запрос
+	pingTimeout = time.Second
+	// maxPingCount ограничение на число запросов за одну проверку
+	maxPingCount = 100
+
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// echoIDs счетчик идентификаторов эхо-запросов. raw сокет получает ответы на
+// все запросы процесса, поэтому у каждой проверки свой идентификатор
+var echoIDs atomic.Uint32
+
+// PingStats статистика ping проверки; время в миллисекундах
+type PingStats struct {
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	Loss     float64 `json:"loss"` // процент потерянных пакетов
+	MinRTT   float64 `json:"min_rtt"`
+	AvgRTT   float64 `json:"avg_rtt"`
+	MaxRTT   float64 `json:"max_rtt"`
+}
+
+func runICMP(ctx context.Context, service models.Service) Result {
+	cfg := icmpConfig(service.ProbeConfig)
+	result := Result{CheckedAt: time.Now()}
+
+	d, err := probeDialer(service.ProbeConfig)
+	var rtts []time.Duration
+	var sent int
+	if err == nil {
+		rtts, sent, err = ping(ctx, d, service.URL, cfg.Count, time.Duration(cfg.IntervalMs)*time.Millisecond)
+	}
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	stats := pingStats(sent, rtts)
+	result.Ping = &stats
+	result.ResponseTime = int(math.Round(stats.AvgRTT))
+
+	loss := AssertionResult{Name: "packet_loss", Actual: fmt.Sprintf("%g%%", stats.Loss)}
+	if cfg.MaxLossPercent > 0 {
+		loss.Expected = fmt.Sprintf("<= %g%%", cfg.MaxLossPercent)
+		loss.Passed = stats.Loss <= cfg.MaxLossPercent
+	} else {
+		loss.Expected = "< 100%"
+		loss.Passed = stats.Received > 0
+	}
+	result.Assertions = append(result.Assertions, loss)
+
+	result.finish()
+	return result
+}
+
+// icmpConfig настройки ping проверки со значениями по умолчанию
+func icmpConfig(cfg models.ProbeConfig) models.ICMPProbeConfig {
+	var c models.ICMPProbeConfig
+	if cfg.ICMP != nil {
+		c = *cfg.ICMP
+	}
+	if c.Count <= 0 {
+		c.Count = pingCount
+	}
+	if c.IntervalMs <= 0 {
+		c.IntervalMs = int(pingInterval.Milliseconds())
+	}
+	return c
+}
+
+// validateICMP проверяет число запросов и допустимую потерю
+func validateICMP(cfg models.ProbeConfig) error {
+	c := icmpConfig(cfg)
+	if c.Count > maxPingCount {
+		return fmt.Errorf("count не может быть больше %d", maxPingCount)
+	}
+	if c.MaxLossPercent < 0 || c.MaxLossPercent > 100 {
+		return errors.New("max_loss_percent должен быть от 0 до 100")
+	}
+	return nil
+}
+
+// pingStats считает потери и RTT по временам полученных ответов
+func pingStats(sent int, rtts []time.Duration) PingStats {
+	stats := PingStats{Sent: sent, Received: len(rtts)}
+	if sent > 0 {
+		stats.Loss = math.Round(float64(sent-len(rtts))/float64(sent)*1000) / 10
+	}
+	if len(rtts) == 0 {
+		return stats
+	}
+
+	var total float64
+	stats.MinRTT = math.Inf(1)
+	for _, rtt := range rtts {
+		ms := float64(rtt.Microseconds()) / 1000
+		total += ms
+		stats.MinRTT = math.Min(stats.MinRTT, ms)
+		stats.MaxRTT = math.Max(stats.MaxRTT, ms)
+	}
+	stats.AvgRTT = math.Round(total/float64(len(rtts))*1000) / 1000
+	return stats
+}
+
+// ping отправляет count эхо-запросов и возвращает времена полученных ответов
+// и число отправленных запросов
+func ping(ctx context.Context, d *dialer, host string, count int, interval time.Duration) ([]time.Duration, int, error) {
+	ips, err := d.lookupIP(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+
+	conn, privileged, err := listenICMP(ip.To4() != nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := protocolICMP
+	if ip.To4() == nil {
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, protocolICMPv6
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	id := int((uint32(os.Getpid()) + echoIDs.Add(1)) & 0xffff)
+	var rtts []time.Duration
+	sent := 0
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+				return rtts, sent, nil
+			case <-time.After(interval):
+			}
+		}
+
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("service-monitor")},
+		}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, sent, err
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			return nil, sent, err
+		}
+		sent++
+
+		// Непривилегированный сокет сам подставляет идентификатор, сверяем только номер
+		if awaitEchoReply(ctx, conn, ip, proto, replyType, seq, id, privileged) {
+			rtts = append(rtts, time.Since(start))
+		}
+	}
+	return rtts, sent, nil
+}
+
+// listenICMP открывает непривилегированный ICMP сокет, а если система его
+// не разрешает - raw сокет (нужны права root или CAP_NET_RAW)
+func listenICMP(v4 bool) (*icmp.PacketConn, bool, error) {
+	network, address, raw := "udp6", "::", "ip6:ipv6-icmp"
+	if v4 {
+		network, address, raw = "udp4", "0.0.0.0", "ip4:icmp"
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, rawErr := icmp.ListenPacket(raw, address)
+	if rawErr != nil {
+		return nil, false, fmt.Errorf("нет доступа к ICMP сокету: %w", errors.Join(err, rawErr))
+	}
+	return conn, true, nil
+}
+
+// awaitEchoReply ждет эхо-ответ от ip с номером seq не дольше pingTimeout
+func awaitEchoReply(ctx context.Context, conn *icmp.PacketConn, ip net.IP, proto int, replyType icmp.Type,
+	seq, id int, checkID bool) bool {
+	deadline := time.Now().Add(pingTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		if isEchoReply(buf[:n], peer, ip, proto, replyType, seq, id, checkID) {
+			return true
+		}
+	}
+}
+
+// isEchoReply сообщает, что пакет - ответ от ip на эхо-запрос seq. Одновременные
+// проверки разных хостов не должны засчитывать ответы друг друга
+func isEchoReply(packet []byte, peer net.Addr, ip net.IP, proto int, replyType icmp.Type, seq, id int, checkID bool) bool {
+	var from net.IP
+	switch addr := peer.(type) {
+	case *net.IPAddr:
+		from = addr.IP
+	case *net.UDPAddr:
+		from = addr.IP
+	}
+	if !from.Equal(ip) {
+		return false
+	}
+
+	msg, err := icmp.ParseMessage(proto, packet)
+	if err != nil || msg.Type != replyType {
+		return false
+	}
+	echo, ok := msg.Body.(*icmp.Echo)
+	return ok && echo.Seq == seq && (!checkID || echo.ID == id)
+}
@@ -0,0 +1,393 @@
+package probe
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"service-monitor/internal/models"
+)
+
+// defaultNetwork сетевые настройки по умолчанию для всех проверок
+var defaultNetwork models.NetworkConfig
+
+// SetDefaultNetwork задает сетевые настройки по умолчанию. Вызывается
+// при запуске, до первой проверки
+func SetDefaultNetwork(cfg models.NetworkConfig) {
+	defaultNetwork = cfg
+}
+
+// networkConfig сетевые настройки сервиса поверх настроек по умолчанию
+func networkConfig(cfg models.ProbeConfig) models.NetworkConfig {
+	n := defaultNetwork
+	if cfg.Network == nil {
+		return n
+	}
+	if cfg.Network.Proxy != "" {
+		n.Proxy = cfg.Network.Proxy
+	}
+	if cfg.Network.Resolver != "" {
+		n.Resolver = cfg.Network.Resolver
+	}
+	if cfg.Network.IPVersion != 0 {
+		n.IPVersion = cfg.Network.IPVersion
+	}
+	return n
+}
+
+// dialer устанавливает соединения проверок с учетом резолвера, версии IP
+// и прокси
+type dialer struct {
+	// server соединяется с прокси; target - с сервисом напрямую
+	server  net.Dialer
+	target  net.Dialer
+	version string
+	proxy   *url.URL
+}
+
+func newDialer(n models.NetworkConfig) (*dialer, error) {
+	d := &dialer{}
+	if n.Resolver != "" {
+		d.server.Resolver = newResolver(n.Resolver)
+		d.target.Resolver = d.server.Resolver
+	}
+	if n.IPVersion != 0 {
+		d.version = strconv.Itoa(n.IPVersion)
+	}
+	if n.Proxy != "" && n.Proxy != models.ProxyDirect {
+		u, err := parseProxy(n.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		d.proxy = u
+	}
+	return d, nil
+}
+
+// probeDialer соединитель для настроек проверки сервиса
+func probeDialer(cfg models.ProbeConfig) (*dialer, error) {
+	return newDialer(networkConfig(cfg))
+}
+
+// DialContext соединяется с адресом; TCP соединения идут через прокси, если он задан
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.proxy == nil || !strings.HasPrefix(network, "tcp") {
+		return d.target.DialContext(ctx, d.network(network), address)
+	}
+
+	if d.proxy.Scheme == "socks5" || d.proxy.Scheme == "socks5h" {
+		socks, err := proxy.FromURL(d.proxy, forwardDialer{&d.server})
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	}
+	return d.dialConnect(ctx, address)
+}
+
+// Dial и DialTimeout нужны драйверу Postgres
+func (d *dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *dialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+// network сеть с учетом версии IP: tcp4, udp6 и т.п.
+func (d *dialer) network(network string) string {
+	if d.version == "" || strings.HasSuffix(network, "4") || strings.HasSuffix(network, "6") {
+		return network
+	}
+	return network + d.version
+}
+
+// lookupIP разрешает имя с учетом резолвера и версии IP
+func (d *dialer) lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	resolver := d.target.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return resolver.LookupIP(ctx, "ip"+d.version, host)
+}
+
+// dialConnect открывает туннель к адресу через HTTP прокси методом CONNECT
+func (d *dialer) dialConnect(ctx context.Context, address string) (net.Conn, error) {
+	proxyAddress := d.proxy.Host
+	if d.proxy.Port() == "" {
+		port := "80"
+		if d.proxy.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(d.proxy.Hostname(), port)
+	}
+
+	conn, err := d.server.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("прокси: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("прокси: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("прокси: %w", err)
+	}
+	// Тело успешного ответа на CONNECT - уже данные туннеля, его не читаем
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("прокси: %s", resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn соединение, часть данных которого уже прочитана в буфер
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// forwardDialer соединение с SOCKS5 прокси
+type forwardDialer struct {
+	d *net.Dialer
+}
+
+func (f forwardDialer) Dial(network, address string) (net.Conn, error) {
+	return f.d.Dial(network, address)
+}
+
+func (f forwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f.d.DialContext(ctx, network, address)
+}
+
+// sharedTransport общий транспорт HTTP проверок без собственных сетевых
+// настроек и TLS: соединения с сервисами переиспользуются между проверками.
+// Число простаивающих соединений не ограничено, они закрываются по IdleConnTimeout
+var sharedTransport = newSharedTransport()
+
+func newSharedTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 0
+	return transport
+}
+
+// SetHostConcurrency задает, сколько соединений с одним хостом держать
+// открытыми между проверками: по числу одновременных проверок хоста.
+// Вызывается при запуске, до первой проверки
+func SetHostConcurrency(n int) {
+	if n > 0 {
+		sharedTransport.MaxIdleConnsPerHost = n
+	}
+}
+
+// maxTransports сколько транспортов с собственными настройками держать. Каждое
+// изменение TLS или сети сервиса дает новый транспорт, старые вытесняются
+const maxTransports = 256
+
+// transportCache транспорты с собственными сетевыми настройками и TLS,
+// вытесняемые по давности использования. Транспорт переиспользуется между
+// проверками, чтобы не копить открытые соединения; у вытесненного
+// закрываются простаивающие соединения, а соединения идущих проверок
+// закроются по IdleConnTimeout
+type transportCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // от недавно использованных к давним
+	items map[[sha256.Size]byte]*list.Element
+}
+
+type cachedTransport struct {
+	key       [sha256.Size]byte
+	transport *http.Transport
+}
+
+func newTransportCache(size int) *transportCache {
+	return &transportCache{size: size, order: list.New(), items: make(map[[sha256.Size]byte]*list.Element)}
+}
+
+// get возвращает транспорт по ключу, создавая его через create
+func (c *transportCache) get(key [sha256.Size]byte, create func() (*http.Transport, error)) (*http.Transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedTransport).transport, nil
+	}
+
+	transport, err := create()
+	if err != nil {
+		return nil, err
+	}
+	c.items[key] = c.order.PushFront(&cachedTransport{key: key, transport: transport})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*cachedTransport)
+		delete(c.items, evicted.key)
+		evicted.transport.CloseIdleConnections()
+	}
+	return transport, nil
+}
+
+var transports = newTransportCache(maxTransports)
+
+// httpClient клиент HTTP проверки с учетом сетевых настроек и TLS сервиса
+func httpClient(cfg models.ProbeConfig) (*http.Client, error) {
+	n := networkConfig(cfg)
+	var tlsSettings models.TLSConfig
+	if cfg.TLS != nil {
+		tlsSettings = *cfg.TLS
+	}
+	if tlsSettings == (models.TLSConfig{}) && n == (models.NetworkConfig{}) {
+		return &http.Client{Transport: sharedTransport}, nil
+	}
+
+	key, err := json.Marshal([]interface{}{tlsSettings, n})
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := transports.get(sha256.Sum256(key), func() (*http.Transport, error) {
+		var tlsConfig *tls.Config
+		if tlsSettings != (models.TLSConfig{}) {
+			var err error
+			if tlsConfig, err = clientTLS(tlsSettings); err != nil {
+				return nil, err
+			}
+		}
+		d, err := newDialer(n)
+		if err != nil {
+			return nil, err
+		}
+		return d.transport(n, tlsConfig), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// transport HTTP транспорт для сетевых настроек и TLS сервиса
+func (d *dialer) transport(n models.NetworkConfig, tlsConfig *tls.Config) *http.Transport {
+	transport := sharedTransport.Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	switch {
+	case d.proxy != nil:
+		// Через прокси адрес сервиса разрешает прокси
+		transport.Proxy = http.ProxyURL(d.proxy)
+		transport.DialContext = d.server.DialContext
+	case n.Proxy == models.ProxyDirect:
+		transport.Proxy = nil
+		transport.DialContext = d.DialContext
+	default:
+		transport.DialContext = d.DialContext
+	}
+	return transport
+}
+
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("неверный адрес прокси: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New("прокси должен начинаться с http://, https://, socks5:// или socks5h://")
+	}
+	if u.Host == "" {
+		return nil, errors.New("в адресе прокси нет хоста")
+	}
+	return u, nil
+}
+
+// validateNetwork проверяет сетевые настройки; прокси поддерживают только
+// TCP проверки
+func validateNetwork(cfg models.ProbeConfig) error {
+	n := cfg.Network
+	if n == nil {
+		return nil
+	}
+
+	if n.Proxy != "" && n.Proxy != models.ProxyDirect {
+		if _, err := parseProxy(n.Proxy); err != nil {
+			return err
+		}
+		switch cfg.Type {
+		case models.ProbeDNS, models.ProbeICMP, models.ProbeUDP:
+			return fmt.Errorf("проверка %s не поддерживает прокси", cfg.Type)
+		}
+	}
+	if n.Resolver != "" {
+		if err := validateResolver(n.Resolver); err != nil {
+			return err
+		}
+	}
+	if n.IPVersion != 0 && n.IPVersion != 4 && n.IPVersion != 6 {
+		return errors.New("ip_version может быть 4 или 6")
+	}
+	return nil
+}
+
+// ValidateDefaultNetwork проверяет сетевые настройки по умолчанию из конфигурации
+func ValidateDefaultNetwork(n models.NetworkConfig) error {
+	return validateNetwork(models.ProbeConfig{Network: &n})
+}
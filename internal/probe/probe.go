@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// Result результат одной проверки сервиса
+type Result struct {
+	Status       string            `json:"status"`
+	ResponseTime int               `json:"response_time"`
+	StatusCode   int               `json:"status_code,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Assertions   []AssertionResult `json:"assertions"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+	CheckedAt    time.Time         `json:"checked_at"`
+	Timings      *models.Timings   `json:"timings,omitempty"`
+	// Answers ответы DNS проверки, нормализованные и отсортированные
+	Answers []string `json:"answers,omitempty"`
+	// Ping потери и RTT ping проверки
+	Ping *PingStats `json:"ping,omitempty"`
+	// Database подробности проверки базы данных
+	Database *DatabaseResult `json:"database,omitempty"`
+	// Steps результаты шагов сценария
+	Steps []StepResult `json:"steps,omitempty"`
+}
+
+// AssertionResult результат одного условия проверки
+type AssertionResult struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Passed   bool   `json:"passed"`
+}
+
+// Run выполняет проверку сервиса с учетом его таймаута
+func Run(ctx context.Context, service models.Service) Result {
+	timeout := time.Duration(service.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch service.ProbeConfig.Type {
+	case models.ProbeScenario:
+		return runScenario(ctx, service)
+	case models.ProbeDNS:
+		return runDNS(ctx, service)
+	case models.ProbeICMP:
+		return runICMP(ctx, service)
+	case models.ProbeUDP:
+		return runUDP(ctx, service)
+	case models.ProbeGRPC:
+		return runGRPC(ctx, service)
+	case models.ProbePostgres, models.ProbeMySQL:
+		return runSQL(ctx, service, service.ProbeConfig.Type)
+	case models.ProbeRedis:
+		return runRedis(ctx, service)
+	default:
+		return runHTTP(ctx, service)
+	}
+}
+
+// Validate проверяет тип и настройки проверки
+func Validate(cfg models.ProbeConfig) error {
+	if err := validateNetwork(cfg); err != nil {
+		return err
+	}
+
+	switch cfg.Type {
+	case "", models.ProbeHTTP:
+		return validateAuth(cfg)
+	case models.ProbeScenario:
+		return validateScenario(cfg)
+	case models.ProbeDNS:
+		return validateDNS(cfg)
+	case models.ProbeICMP:
+		return validateICMP(cfg)
+	case models.ProbeUDP:
+		return validateUDP(cfg)
+	case models.ProbeGRPC:
+		return validateGRPC(cfg)
+	case models.ProbePostgres, models.ProbeMySQL, models.ProbeRedis:
+		return validateDatabase(cfg)
+	default:
+		return fmt.Errorf("неизвестный тип проверки %s", cfg.Type)
+	}
+}
+
+// finish вычисляет итоговый статус по результатам условий
+func (r *Result) finish() {
+	r.Status = models.StatusHealthy
+	if msg := failedAssertion(r.Assertions); msg != "" {
+		r.Status = models.StatusUnhealthy
+		if r.ErrorMessage == "" {
+			r.ErrorMessage = msg
+		}
+	}
+}
+
+// RewriteMessages применяет rewrite к сообщениям об ошибках и значениям
+// условий результата и его шагов, например чтобы скрыть значения секретов
+func (r *Result) RewriteMessages(rewrite func(string) string) {
+	r.ErrorMessage = rewrite(r.ErrorMessage)
+	rewriteAssertions(r.Assertions, rewrite)
+	for i := range r.Steps {
+		r.Steps[i].ErrorMessage = rewrite(r.Steps[i].ErrorMessage)
+		rewriteAssertions(r.Steps[i].Assertions, rewrite)
+	}
+}
+
+func rewriteAssertions(assertions []AssertionResult, rewrite func(string) string) {
+	for i := range assertions {
+		assertions[i].Expected = rewrite(assertions[i].Expected)
+		assertions[i].Actual = rewrite(assertions[i].Actual)
+	}
+}
+
+// failedAssertion описание первого невыполненного условия или пустая строка
+func failedAssertion(assertions []AssertionResult) string {
+	for _, a := range assertions {
+		if !a.Passed {
+			return a.Name + ": ожидалось " + a.Expected + ", получено " + a.Actual
+		}
+	}
+	return ""
+}
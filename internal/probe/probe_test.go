@@ -0,0 +1,695 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"service-monitor/internal/models"
+)
+
+// fakeDNS локальный DNS сервер, отвечающий заранее заданными записями
+type fakeDNS struct {
+	conn    net.PacketConn
+	records map[dnsmessage.Type][]dnsmessage.ResourceBody
+}
+
+func startFakeDNS(t *testing.T, records map[dnsmessage.Type][]dnsmessage.ResourceBody) *fakeDNS {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	f := &fakeDNS{conn: conn, records: records}
+	go f.serve()
+	return f
+}
+
+func (f *fakeDNS) addr() string {
+	return f.conn.LocalAddr().String()
+}
+
+func (f *fakeDNS) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := f.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil {
+			continue
+		}
+		question, err := p.Question()
+		if err != nil {
+			continue
+		}
+
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+		b.EnableCompression()
+		b.StartQuestions()
+		b.Question(question)
+		b.StartAnswers()
+
+		rh := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		for _, body := range f.records[question.Type] {
+			switch r := body.(type) {
+			case *dnsmessage.AResource:
+				b.AResource(rh, *r)
+			case *dnsmessage.AAAAResource:
+				b.AAAAResource(rh, *r)
+			case *dnsmessage.CNAMEResource:
+				b.CNAMEResource(rh, *r)
+			case *dnsmessage.MXResource:
+				b.MXResource(rh, *r)
+			case *dnsmessage.TXTResource:
+				b.TXTResource(rh, *r)
+			case *dnsmessage.SRVResource:
+				b.SRVResource(rh, *r)
+			}
+		}
+
+		msg, err := b.Finish()
+		if err != nil {
+			continue
+		}
+		f.conn.WriteTo(msg, addr)
+	}
+}
+
+func dnsService(server *fakeDNS, name string, cfg models.DNSProbeConfig) models.Service {
+	cfg.Resolver = server.addr()
+	return models.Service{
+		URL:         name,
+		Timeout:     2,
+		ProbeConfig: models.ProbeConfig{Type: models.ProbeDNS, DNS: &cfg},
+	}
+}
+
+func TestDNSProbe(t *testing.T) {
+	server := startFakeDNS(t, map[dnsmessage.Type][]dnsmessage.ResourceBody{
+		dnsmessage.TypeA: {
+			&dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}},
+			&dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+		},
+		dnsmessage.TypeMX: {
+			&dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("Mail.Example.com.")},
+		},
+		dnsmessage.TypeTXT: {
+			&dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}},
+		},
+		dnsmessage.TypeSRV: {
+			&dnsmessage.SRVResource{Priority: 1, Weight: 5, Port: 5060, Target: dnsmessage.MustNewName("sip.example.com.")},
+		},
+	})
+
+	result := Run(context.Background(), dnsService(server, "example.com", models.DNSProbeConfig{
+		Expected: []string{"10.0.0.1", "10.0.0.2"},
+	}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, result.Answers)
+
+	result = Run(context.Background(), dnsService(server, "example.com", models.DNSProbeConfig{
+		Expected: []string{"10.0.0.1"},
+	}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, "answers: ожидалось 10.0.0.1, получено 10.0.0.1, 10.0.0.2", result.ErrorMessage)
+
+	result = Run(context.Background(), dnsService(server, "example.com", models.DNSProbeConfig{
+		RecordType: "mx",
+		Expected:   []string{"10 mail.example.com."},
+	}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, []string{"10 mail.example.com"}, result.Answers)
+
+	result = Run(context.Background(), dnsService(server, "example.com", models.DNSProbeConfig{RecordType: models.RecordTXT}))
+	assert.Equal(t, []string{"v=spf1 -all"}, result.Answers)
+
+	result = Run(context.Background(), dnsService(server, "_sip._tcp.example.com", models.DNSProbeConfig{RecordType: models.RecordSRV}))
+	assert.Equal(t, []string{"1 5 5060 sip.example.com"}, result.Answers)
+
+	// Записей нужного типа нет
+	result = Run(context.Background(), dnsService(server, "example.com", models.DNSProbeConfig{RecordType: models.RecordAAAA}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Empty(t, result.Answers)
+}
+
+func TestPingStats(t *testing.T) {
+	stats := pingStats(4, []time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond})
+	assert.Equal(t, PingStats{Sent: 4, Received: 3, Loss: 25, MinRTT: 10, AvgRTT: 20, MaxRTT: 30}, stats)
+
+	stats = pingStats(3, nil)
+	assert.Equal(t, PingStats{Sent: 3, Loss: 100}, stats)
+}
+
+func TestIsEchoReply(t *testing.T) {
+	target := net.ParseIP("10.0.0.1")
+	reply, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeEchoReply,
+		Body: &icmp.Echo{ID: 7, Seq: 2, Data: []byte("service-monitor")},
+	}).Marshal(nil)
+	assert.NoError(t, err)
+
+	from := &net.IPAddr{IP: target}
+	assert.True(t, isEchoReply(reply, from, target, protocolICMP, ipv4.ICMPTypeEchoReply, 2, 7, true))
+
+	// Ответ другому хосту, на другой номер или с чужим идентификатором не засчитывается
+	other := &net.IPAddr{IP: net.ParseIP("10.0.0.2")}
+	assert.False(t, isEchoReply(reply, other, target, protocolICMP, ipv4.ICMPTypeEchoReply, 2, 7, true))
+	assert.False(t, isEchoReply(reply, from, target, protocolICMP, ipv4.ICMPTypeEchoReply, 3, 7, true))
+	assert.False(t, isEchoReply(reply, from, target, protocolICMP, ipv4.ICMPTypeEchoReply, 2, 8, true))
+
+	// Непривилегированный сокет подставляет свой идентификатор, адрес - UDPAddr
+	assert.True(t, isEchoReply(reply, &net.UDPAddr{IP: target}, target, protocolICMP, ipv4.ICMPTypeEchoReply, 2, 8, false))
+}
+
+func TestICMPProbe(t *testing.T) {
+	conn, _, err := listenICMP(true)
+	if err != nil {
+		t.Skip("ICMP сокеты недоступны:", err)
+	}
+	conn.Close()
+
+	result := Run(context.Background(), models.Service{
+		URL:         "127.0.0.1",
+		Timeout:     5,
+		ProbeConfig: models.ProbeConfig{Type: models.ProbeICMP, ICMP: &models.ICMPProbeConfig{Count: 2, IntervalMs: 10}},
+	})
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, 2, result.Ping.Received)
+	assert.Equal(t, 0.0, result.Ping.Loss)
+}
+
+// startUDPEcho локальный UDP сервер, отвечающий на датаграмму ее копией в верхнем регистре
+func startUDPEcho(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo([]byte(strings.ToUpper(string(buf[:n]))), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestUDPProbe(t *testing.T) {
+	addr := startUDPEcho(t)
+	udpService := func(cfg models.UDPProbeConfig) models.Service {
+		return models.Service{URL: addr, Timeout: 1, ProbeConfig: models.ProbeConfig{Type: models.ProbeUDP, UDP: &cfg}}
+	}
+
+	result := Run(context.Background(), udpService(models.UDPProbeConfig{Send: "ping", Expect: "PING"}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	result = Run(context.Background(), udpService(models.UDPProbeConfig{SendHex: "1b00", ExpectHex: "1b00"}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	result = Run(context.Background(), udpService(models.UDPProbeConfig{Send: "ping", ExpectHex: "ff"}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, "response_prefix: ожидалось ff, получено 50", result.ErrorMessage)
+
+	// Порт никто не слушает: ответа нет
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	silent := closed.LocalAddr().String()
+	closed.Close()
+
+	service := udpService(models.UDPProbeConfig{Send: "ping"})
+	service.URL = silent
+	result = Run(context.Background(), service)
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+}
+
+func TestGRPCProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Сервер проверяет, что метаданные дошли
+	tokens := make(chan string, 10)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		tokens <- strings.Join(md.Get("x-token"), ",")
+		return handler(ctx, req)
+	}))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus("search", healthpb.HealthCheckResponse_UNKNOWN)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	grpcService := func(name string) models.Service {
+		return models.Service{
+			URL:     listener.Addr().String(),
+			Timeout: 2,
+			ProbeConfig: models.ProbeConfig{Type: models.ProbeGRPC, GRPC: &models.GRPCProbeConfig{
+				Service:  name,
+				Metadata: map[string]string{"X-Token": "secret"},
+			}},
+		}
+	}
+
+	result := Run(context.Background(), grpcService("orders"))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, "secret", <-tokens)
+
+	result = Run(context.Background(), grpcService(""))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	result = Run(context.Background(), grpcService("billing"))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, "gRPC health: NOT_SERVING", result.ErrorMessage)
+
+	result = Run(context.Background(), grpcService("search"))
+	assert.Equal(t, models.StatusDegraded, result.Status)
+
+	result = Run(context.Background(), grpcService("missing"))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, `сервис "missing" не зарегистрирован в gRPC health`, result.ErrorMessage)
+}
+
+// startFakeRedis локальный сервер с паролем secret: отвечает на AUTH, SELECT,
+// PING, INFO и GET; info - ответ на INFO
+func startFakeRedis(t *testing.T, info string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRedis(conn, info)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveRedis(conn net.Conn, info string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := false
+
+	for {
+		var n int
+		if _, err := fmt.Fscanf(reader, "*%d\r\n", &n); err != nil {
+			return
+		}
+		args := make([]string, n)
+		for i := range args {
+			var size int
+			fmt.Fscanf(reader, "$%d\r\n", &size)
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+
+		switch {
+		case strings.ToUpper(args[0]) == "AUTH":
+			authenticated = args[len(args)-1] == "secret"
+			if !authenticated {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case !authenticated:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case strings.ToUpper(args[0]) == "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case strings.ToUpper(args[0]) == "INFO":
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		case strings.ToUpper(args[0]) == "GET":
+			fmt.Fprint(conn, "$2\r\nok\r\n")
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+	}
+}
+
+func TestRedisProbe(t *testing.T) {
+	replica := "# Replication\r\nrole:slave\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:7\r\n"
+	addr := startFakeRedis(t, replica)
+	redisService := func(url string, cfg models.DatabaseProbeConfig) models.Service {
+		return models.Service{URL: url, Timeout: 2, ProbeConfig: models.ProbeConfig{Type: models.ProbeRedis, Database: &cfg}}
+	}
+
+	result := Run(context.Background(), redisService("redis://:secret@"+addr+"/2", models.DatabaseProbeConfig{}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, "PONG", result.Database.Value)
+
+	result = Run(context.Background(), redisService("redis://:wrong@"+addr, models.DatabaseProbeConfig{}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, "аутентификация: WRONGPASS invalid password", result.ErrorMessage)
+
+	result = Run(context.Background(), redisService("redis://:secret@"+addr, models.DatabaseProbeConfig{
+		Command: "GET health", Expected: "ok",
+	}))
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	result = Run(context.Background(), redisService("redis://:secret@"+addr, models.DatabaseProbeConfig{
+		Command: "INFO replication", Expected: "role:master",
+	}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, "result: ожидалось role:master, получено role:slave", result.ErrorMessage)
+
+	result = Run(context.Background(), redisService("redis://:secret@"+addr, models.DatabaseProbeConfig{MaxReplicationLagSeconds: 5}))
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Equal(t, 7.0, *result.Database.ReplicationLag)
+	assert.Equal(t, "replication_lag: ожидалось <= 5 с, получено 7 с", result.ErrorMessage)
+}
+
+func TestSQLProbeConfig(t *testing.T) {
+	cfg, err := mysqlConfig("monitor:secret@tcp(db:3306)/app?timeout=5s")
+	if assert.NoError(t, err) {
+		assert.Equal(t, mysqlNetwork, cfg.Net)
+		assert.Equal(t, "db:3306", cfg.Addr)
+		assert.Equal(t, "app", cfg.DBName)
+	}
+	// Unix-сокет открывается напрямую, без соединителя проверки
+	cfg, err = mysqlConfig("monitor@unix(/run/mysqld/mysqld.sock)/app")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "unix", cfg.Net)
+	}
+	_, err = mysqlConfig("db:3306")
+	assert.Error(t, err)
+
+	postgres := func(url string) error {
+		db, err := openDatabase(models.Service{URL: url}, models.ProbePostgres)
+		if err == nil {
+			db.Close()
+		}
+		return err
+	}
+	assert.NoError(t, postgres("postgres://monitor:secret@db:5432/app?sslmode=disable"))
+	assert.NoError(t, postgres("host=db port=5432 user=monitor dbname=app sslmode=disable"))
+	assert.Error(t, postgres("postgres://db:port/app"))
+	assert.Error(t, postgres("host=db password='unterminated"))
+}
+
+func TestReplicaLag(t *testing.T) {
+	value := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+	lag, err := replicaLag([]string{"Replica_IO_State", "Seconds_Behind_Source"},
+		[]sql.NullString{value("Waiting for source"), value("12")})
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, lag)
+
+	// MySQL до 8.0.22
+	lag, err = replicaLag([]string{"Seconds_Behind_Master"}, []sql.NullString{value("0")})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, lag)
+
+	_, err = replicaLag([]string{"Seconds_Behind_Source"}, []sql.NullString{{}})
+	assert.EqualError(t, err, "репликация остановлена")
+	_, err = replicaLag([]string{"Replica_IO_State"}, []sql.NullString{value("")})
+	assert.EqualError(t, err, "нет колонки Seconds_Behind_Source")
+	_, err = replicaLag([]string{"Seconds_Behind_Source"}, []sql.NullString{value("n/a")})
+	assert.Error(t, err)
+
+	assert.Equal(t, AssertionResult{Name: "replication_lag", Expected: "<= 1.5 с", Actual: "2 с"}, lagAssertion(2, 1.5))
+	assert.True(t, lagAssertion(1.5, 1.5).Passed)
+}
+
+func TestValueAssertion(t *testing.T) {
+	assert.True(t, valueAssertion("1", "1").Passed)
+	// Значение сравнивается как строка, без приведения типов
+	assert.False(t, valueAssertion("t", "true").Passed)
+	assert.False(t, valueAssertion("1.0", "1").Passed)
+	assert.Equal(t, "result: ожидалось ok, получено ", failedAssertion([]AssertionResult{valueAssertion("", "ok")}))
+}
+
+func TestScenarioProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		fmt.Fprint(w, `{"data": {"items": [{"token": "t-42"}]}}`)
+	})
+	mux.HandleFunc("/cart", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "abc" || r.Header.Get("Authorization") != "Bearer t-42" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"items": 3}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := models.Service{URL: server.URL, ProbeConfig: models.ProbeConfig{
+		Type: models.ProbeScenario,
+		Scenario: &models.ScenarioProbeConfig{Steps: []models.ScenarioStep{
+			{Name: "вход", Method: "POST", URL: "/login", Body: `{"user": "demo"}`,
+				Extract: []models.Extraction{{Name: "token", JSON: "data.items.0.token"}}},
+			{Name: "корзина", URL: "/cart", Headers: map[string]string{"Authorization": "Bearer {{token}}"},
+				BodyContains: `"items"`},
+		}},
+	}}
+
+	result := Run(context.Background(), service)
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Len(t, result.Steps, 2)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+
+	service.ProbeConfig.Scenario.Steps[0].Extract[0].JSON = "data.items.1.token"
+	result = Run(context.Background(), service)
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Len(t, result.Steps, 1)
+	assert.Contains(t, result.ErrorMessage, `шаг 1 "вход"`)
+
+	service.ProbeConfig.Scenario.Steps[0].Extract = nil
+	result = Run(context.Background(), service)
+	assert.Equal(t, models.StatusUnhealthy, result.Status)
+	assert.Contains(t, result.ErrorMessage, `шаг 2 "корзина": переменная token не определена`)
+}
+
+func TestHTTPProbeOAuth2(t *testing.T) {
+	issued := 0
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "monitor" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued++
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 3600}`, issued)
+	}))
+	defer tokens.Close()
+
+	accepted := "Bearer token-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != accepted {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	service := models.Service{URL: server.URL, ProbeConfig: models.ProbeConfig{Auth: &models.AuthConfig{
+		Type:   models.AuthOAuth2,
+		OAuth2: &models.OAuth2Config{TokenURL: tokens.URL, ClientID: "monitor", ClientSecret: "s3cret"},
+	}}}
+
+	assert.Equal(t, models.StatusHealthy, Run(context.Background(), service).Status)
+	assert.Equal(t, models.StatusHealthy, Run(context.Background(), service).Status)
+	assert.Equal(t, 1, issued)
+
+	// Отозванный токен сбрасывается, следующая проверка получает новый
+	accepted = "Bearer token-2"
+	assert.Equal(t, models.StatusUnhealthy, Run(context.Background(), service).Status)
+	assert.Equal(t, models.StatusHealthy, Run(context.Background(), service).Status)
+	assert.Equal(t, 2, issued)
+}
+
+func TestHTTPProbeTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "admin" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	auth := &models.AuthConfig{Type: models.AuthBasic, Username: "admin", Password: "pass"}
+	service := models.Service{URL: server.URL, ProbeConfig: models.ProbeConfig{Auth: auth}}
+	assert.Equal(t, models.StatusUnhealthy, Run(context.Background(), service).Status)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	service.ProbeConfig.TLS = &models.TLSConfig{CACert: string(ca)}
+	result := Run(context.Background(), service)
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	service.ProbeConfig.TLS = &models.TLSConfig{InsecureSkipVerify: true}
+	assert.Equal(t, models.StatusHealthy, Run(context.Background(), service).Status)
+}
+
+func TestHTTPProbeProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	service := models.Service{URL: "http://service.internal/health", ProbeConfig: models.ProbeConfig{
+		Network: &models.NetworkConfig{Proxy: proxy.URL},
+	}}
+	result := Run(context.Background(), service)
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+	assert.Equal(t, "http://service.internal/health", proxied)
+}
+
+func TestDialConnectProxy(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer target.Close()
+	go func() {
+		conn, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		buf.Flush()
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	d, err := newDialer(models.NetworkConfig{Proxy: strings.Replace(proxy.URL, "http://", "http://user:pass@", 1)})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := d.DialContext(ctx, "tcp", target.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "ping")
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+
+	d, _ = newDialer(models.NetworkConfig{Proxy: proxy.URL})
+	_, err = d.DialContext(ctx, "tcp", target.Addr().String())
+	assert.ErrorContains(t, err, "407")
+}
+
+func TestTransportCacheEviction(t *testing.T) {
+	cache := newTransportCache(2)
+	created := 0
+	create := func() (*http.Transport, error) {
+		created++
+		return &http.Transport{}, nil
+	}
+	key := func(b byte) [sha256.Size]byte { return [sha256.Size]byte{b} }
+
+	first, err := cache.get(key(1), create)
+	assert.NoError(t, err)
+	_, _ = cache.get(key(2), create)
+	again, _ := cache.get(key(1), create)
+	assert.Same(t, first, again)
+
+	// Ключ 2 использовался давнее всех и вытесняется
+	_, _ = cache.get(key(3), create)
+	assert.Equal(t, 2, cache.order.Len())
+	assert.Contains(t, cache.items, key(1))
+	assert.NotContains(t, cache.items, key(2))
+
+	_, _ = cache.get(key(2), create)
+	assert.Equal(t, 4, created)
+	assert.Len(t, cache.items, 2)
+}
+
+func TestHTTPProbeIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	service := models.Service{URL: "http://localhost:" + port, ProbeConfig: models.ProbeConfig{
+		Network: &models.NetworkConfig{IPVersion: 4},
+	}}
+	result := Run(context.Background(), service)
+	assert.Equal(t, models.StatusHealthy, result.Status, result.ErrorMessage)
+
+	// Сервер слушает только 127.0.0.1
+	service.ProbeConfig.Network.IPVersion = 6
+	assert.Equal(t, models.StatusUnhealthy, Run(context.Background(), service).Status)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(models.ProbeConfig{}))
+	assert.NoError(t, Validate(models.ProbeConfig{Type: models.ProbeDNS}))
+	assert.NoError(t, Validate(models.ProbeConfig{Type: models.ProbeDNS, DNS: &models.DNSProbeConfig{RecordType: "srv", Resolver: "1.1.1.1"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeDNS, DNS: &models.DNSProbeConfig{RecordType: "PTR"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeICMP, ICMP: &models.ICMPProbeConfig{MaxLossPercent: 150}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeUDP, UDP: &models.UDPProbeConfig{SendHex: "zz"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeGRPC, GRPC: &models.GRPCProbeConfig{Metadata: map[string]string{"grpc-timeout": "1s"}}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbePostgres, Database: &models.DatabaseProbeConfig{Command: "PING"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeScenario}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeScenario, Scenario: &models.ScenarioProbeConfig{
+		Steps: []models.ScenarioStep{{URL: "/", Extract: []models.Extraction{{Name: "token", JSON: "token", Header: "X-Token"}}}}}}))
+	assert.Error(t, Validate(models.ProbeConfig{Auth: &models.AuthConfig{Type: models.AuthOAuth2}}))
+	assert.Error(t, Validate(models.ProbeConfig{TLS: &models.TLSConfig{CACert: "not a pem"}}))
+	assert.NoError(t, Validate(models.ProbeConfig{Network: &models.NetworkConfig{Proxy: "socks5h://proxy:1080", IPVersion: 6}}))
+	assert.Error(t, Validate(models.ProbeConfig{Network: &models.NetworkConfig{Proxy: "ftp://proxy"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: models.ProbeICMP, Network: &models.NetworkConfig{Proxy: "http://proxy:3128"}}))
+	assert.Error(t, Validate(models.ProbeConfig{Network: &models.NetworkConfig{IPVersion: 5}}))
+	assert.Error(t, Validate(models.ProbeConfig{Type: "smtp"}))
+}
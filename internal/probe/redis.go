@@ -0,0 +1,243 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// redisPort порт Redis, если в URL он не указан
+const redisPort = "6379"
+
+// runRedis подключается к Redis по URL redis://[user:password@]host[:port][/db]
+// (rediss:// - с TLS) и выполняет команду, по умолчанию PING
+func runRedis(ctx context.Context, service models.Service) Result {
+	cfg := databaseConfig(service.ProbeConfig)
+	result := Result{CheckedAt: time.Now()}
+
+	start := time.Now()
+	client, err := dialRedis(ctx, service.ProbeConfig, service.URL)
+	if err != nil {
+		result.ResponseTime = int(time.Since(start).Milliseconds())
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	defer client.Close()
+	connected := time.Now()
+
+	command := strings.Fields(cfg.Command)
+	if len(command) == 0 {
+		command = []string{"PING"}
+	}
+	value, err := client.do(command...)
+	queried := time.Now()
+
+	result.ResponseTime = int(queried.Sub(connected).Milliseconds())
+	result.Database = &DatabaseResult{
+		ConnectTime: millis(start, connected),
+		QueryTime:   millis(connected, queried),
+		Value:       value,
+	}
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	if cfg.Expected != "" {
+		a := AssertionResult{Name: "result", Expected: cfg.Expected, Actual: value, Passed: value == cfg.Expected}
+		if strings.EqualFold(command[0], "INFO") {
+			key, _, _ := strings.Cut(cfg.Expected, ":")
+			a.Actual = key + ":" + parseInfo(value)[key]
+			a.Passed = a.Actual == cfg.Expected
+		}
+		result.Assertions = append(result.Assertions, a)
+	}
+
+	if cfg.MaxReplicationLagSeconds > 0 {
+		lag, err := redisLag(client)
+		if err != nil {
+			result.Status = models.StatusUnhealthy
+			result.ErrorMessage = "отставание реплики: " + err.Error()
+			return result
+		}
+		result.Database.ReplicationLag = &lag
+		result.Assertions = append(result.Assertions, lagAssertion(lag, cfg.MaxReplicationLagSeconds))
+	}
+
+	result.finish()
+	return result
+}
+
+// redisClient минимальный клиент протокола RESP для проверок
+type redisClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialRedis подключается, аутентифицируется и выбирает базу из URL
+func dialRedis(ctx context.Context, cfg models.ProbeConfig, rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("адрес Redis должен начинаться с redis:// или rediss://")
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), redisPort)
+	}
+
+	d, err := probeDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if u.Scheme == "rediss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	client := &redisClient{conn: conn, reader: bufio.NewReader(conn)}
+
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := client.do(args...); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("аутентификация: %w", err)
+		}
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err := strconv.Atoi(db); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("неверный номер базы %s", db)
+		}
+		if _, err := client.do("SELECT", db); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+func (c *redisClient) Close() error {
+	return c.conn.Close()
+}
+
+// do отправляет команду и возвращает ответ строкой; элементы массива
+// разделяются переводом строки, ответ-ошибка возвращается как error
+func (c *redisClient) do(args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return "", err
+	}
+	return c.readReply()
+}
+
+func (c *redisClient) readReply() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("пустой ответ Redis")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		items := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := c.readReply()
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return strings.Join(items, "\n"), nil
+	}
+	return "", fmt.Errorf("неизвестный ответ Redis: %q", line)
+}
+
+// redisLag отставание реплики Redis по INFO replication: секунды с последнего
+// обмена с основным сервером; у основного сервера 0
+func redisLag(client *redisClient) (float64, error) {
+	reply, err := client.do("INFO", "replication")
+	if err != nil {
+		return 0, err
+	}
+
+	info := parseInfo(reply)
+	if info["role"] != "slave" {
+		return 0, nil
+	}
+	if info["master_link_status"] != "up" {
+		return 0, fmt.Errorf("связь с основным сервером: %s", info["master_link_status"])
+	}
+	return strconv.ParseFloat(info["master_last_io_seconds_ago"], 64)
+}
+
+// parseInfo разбирает ответ INFO в пары ключ-значение
+func parseInfo(reply string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			info[key] = value
+		}
+	}
+	return info
+}
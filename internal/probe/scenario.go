@@ -0,0 +1,270 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// maxScenarioSteps ограничение на число шагов сценария
+const maxScenarioSteps = 20
+
+// scenarioVar подстановка переменной сценария: {{name}}
+var scenarioVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// StepResult результат шага сценария
+type StepResult struct {
+	Name         string            `json:"name"`
+	StatusCode   int               `json:"status_code,omitempty"`
+	ResponseTime int               `json:"response_time"`
+	Timings      *models.Timings   `json:"timings,omitempty"`
+	Assertions   []AssertionResult `json:"assertions"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+}
+
+// runScenario выполняет шаги по порядку с общими cookie и переменными.
+// Таймаут сервиса действует на весь сценарий; на первом неудачном шаге
+// сценарий останавливается, а шаг называется в сообщении об ошибке
+func runScenario(ctx context.Context, service models.Service) Result {
+	result := Result{CheckedAt: time.Now()}
+
+	var steps []models.ScenarioStep
+	if service.ProbeConfig.Scenario != nil {
+		steps = service.ProbeConfig.Scenario.Steps
+	}
+
+	base, err := url.Parse(service.URL)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	client, err := httpClient(service.ProbeConfig)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	client.Jar, _ = cookiejar.New(nil)
+	vars := make(map[string]string)
+
+	for i, step := range steps {
+		step.Name = stepName(i, step)
+		sr := runStep(ctx, client, service.ProbeConfig.Auth, base, step, vars)
+		result.Steps = append(result.Steps, sr)
+		result.ResponseTime += sr.ResponseTime
+		result.StatusCode = sr.StatusCode
+
+		if sr.ErrorMessage != "" {
+			result.Status = models.StatusUnhealthy
+			result.ErrorMessage = fmt.Sprintf("шаг %d %q: %s", i+1, step.Name, sr.ErrorMessage)
+			return result
+		}
+	}
+
+	result.Status = models.StatusHealthy
+	return result
+}
+
+func stepName(i int, step models.ScenarioStep) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return "шаг " + strconv.Itoa(i+1)
+}
+
+// runStep выполняет один запрос, проверяет ответ и сохраняет извлеченные переменные.
+// Аутентификация сервиса добавляется, если шаг не задал Authorization сам
+func runStep(ctx context.Context, client *http.Client, auth *models.AuthConfig, base *url.URL,
+	step models.ScenarioStep, vars map[string]string) StepResult {
+	sr := StepResult{Name: step.Name}
+
+	req, err := stepRequest(ctx, base, step, vars)
+	if err == nil && req.Header.Get("Authorization") == "" {
+		err = authorize(ctx, client, auth, req)
+	}
+	if err != nil {
+		sr.ErrorMessage = err.Error()
+		return sr
+	}
+
+	resp, body, timings, err := doHTTP(client, req)
+	sr.ResponseTime = int(timings.elapsed.Milliseconds())
+	sr.Timings = timings.phases
+	if err != nil {
+		sr.ErrorMessage = err.Error()
+		return sr
+	}
+	sr.StatusCode = resp.StatusCode
+	rejectAuth(auth, resp.StatusCode)
+
+	sr.Assertions = append(sr.Assertions, checkStatus(step.ExpectedStatus, resp.StatusCode))
+	if step.BodyContains != "" {
+		sr.Assertions = append(sr.Assertions, AssertionResult{
+			Name:     "body_contains",
+			Expected: step.BodyContains,
+			Actual:   fmt.Sprintf("%d байт", len(body)),
+			Passed:   strings.Contains(string(body), step.BodyContains),
+		})
+	}
+	if msg := failedAssertion(sr.Assertions); msg != "" {
+		sr.ErrorMessage = msg
+		return sr
+	}
+
+	for _, e := range step.Extract {
+		value, err := extract(e, resp.Header, body)
+		if err != nil {
+			sr.ErrorMessage = fmt.Sprintf("переменная %s: %v", e.Name, err)
+			return sr
+		}
+		vars[e.Name] = value
+	}
+
+	return sr
+}
+
+// stepRequest строит запрос шага с подставленными переменными
+func stepRequest(ctx context.Context, base *url.URL, step models.ScenarioStep, vars map[string]string) (*http.Request, error) {
+	rawURL, err := substitute(step.URL, vars)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := substitute(step.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, base.ResolveReference(ref).String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range step.Headers {
+		value, err := substitute(value, vars)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// substitute заменяет {{name}} значениями переменных
+func substitute(text string, vars map[string]string) (string, error) {
+	var missing string
+	result := scenarioVar.ReplaceAllStringFunc(text, func(match string) string {
+		name := scenarioVar.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("переменная %s не определена", missing)
+	}
+	return result, nil
+}
+
+// extract достает значение переменной из заголовка или JSON тела ответа
+func extract(e models.Extraction, header http.Header, body []byte) (string, error) {
+	if e.Header != "" {
+		value := header.Get(e.Header)
+		if value == "" {
+			return "", fmt.Errorf("нет заголовка %s", e.Header)
+		}
+		return value, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return "", fmt.Errorf("ответ не JSON: %w", err)
+	}
+
+	value, err := jsonPath(doc, e.JSON)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "", fmt.Errorf("поле %s равно null", e.JSON)
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
+
+// jsonPath находит значение по пути через точку; индексы массивов - числа
+func jsonPath(doc interface{}, path string) (interface{}, error) {
+	value := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("нет поля %s", path)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("нет элемента %s", path)
+			}
+			value = node[i]
+		default:
+			return nil, fmt.Errorf("нет поля %s", path)
+		}
+	}
+	return value, nil
+}
+
+// validateScenario проверяет шаги и правила извлечения переменных
+func validateScenario(cfg models.ProbeConfig) error {
+	if cfg.Scenario == nil || len(cfg.Scenario.Steps) == 0 {
+		return errors.New("в сценарии нет шагов")
+	}
+	if len(cfg.Scenario.Steps) > maxScenarioSteps {
+		return fmt.Errorf("в сценарии не может быть больше %d шагов", maxScenarioSteps)
+	}
+
+	for i, step := range cfg.Scenario.Steps {
+		name := stepName(i, step)
+		if step.URL == "" {
+			return fmt.Errorf("шаг %q: не указан url", name)
+		}
+		for _, e := range step.Extract {
+			if e.Name == "" || (e.JSON == "") == (e.Header == "") {
+				return fmt.Errorf("шаг %q: у переменной нужно указать name и одно из json или header", name)
+			}
+		}
+	}
+	return validateAuth(cfg)
+}
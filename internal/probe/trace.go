@@ -0,0 +1,62 @@
+package probe
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// timingTracer собирает отметки времени фаз HTTP запроса через httptrace
+type timingTracer struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+func (t *timingTracer) clientTrace() *httptrace.ClientTrace {
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+		t.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	}
+}
+
+// timings вычисляет длительность фаз; end - момент окончания чтения тела
+func (t *timingTracer) timings(end time.Time) *models.Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &models.Timings{
+		DNS:      millis(t.dnsStart, t.dnsDone),
+		Connect:  millis(t.connectStart, t.connectDone),
+		TLS:      millis(t.tlsStart, t.tlsDone),
+		TTFB:     millis(t.wroteRequest, t.firstByte),
+		Transfer: millis(t.firstByte, end),
+	}
+}
+
+func millis(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
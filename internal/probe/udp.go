@@ -0,0 +1,126 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// maxDatagramSize ограничение на размер ответа UDP проверки
+const maxDatagramSize = 64 * 1024
+
+func runUDP(ctx context.Context, service models.Service) Result {
+	var cfg models.UDPProbeConfig
+	if service.ProbeConfig.UDP != nil {
+		cfg = *service.ProbeConfig.UDP
+	}
+	result := Result{CheckedAt: time.Now()}
+
+	payload, expectPrefix, err := udpPayloads(cfg)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	d, err := probeDialer(service.ProbeConfig)
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	response, err := exchangeUDP(ctx, d, service.URL, payload)
+	result.ResponseTime = int(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Status = models.StatusUnhealthy
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	if cfg.Expect != "" {
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Name:     "response_contains",
+			Expected: cfg.Expect,
+			Actual:   fmt.Sprintf("%d байт", len(response)),
+			Passed:   bytes.Contains(response, []byte(cfg.Expect)),
+		})
+	}
+	if expectPrefix != nil {
+		actual := response
+		if len(actual) > len(expectPrefix) {
+			actual = actual[:len(expectPrefix)]
+		}
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Name:     "response_prefix",
+			Expected: hex.EncodeToString(expectPrefix),
+			Actual:   hex.EncodeToString(actual),
+			Passed:   bytes.HasPrefix(response, expectPrefix),
+		})
+	}
+
+	result.finish()
+	return result
+}
+
+// udpPayloads декодирует отправляемые данные и ожидаемое начало ответа
+func udpPayloads(cfg models.UDPProbeConfig) ([]byte, []byte, error) {
+	payload := []byte(cfg.Send)
+	if cfg.SendHex != "" {
+		var err error
+		if payload, err = hex.DecodeString(cfg.SendHex); err != nil {
+			return nil, nil, fmt.Errorf("send_hex: %w", err)
+		}
+	}
+
+	var prefix []byte
+	if cfg.ExpectHex != "" {
+		var err error
+		if prefix, err = hex.DecodeString(cfg.ExpectHex); err != nil {
+			return nil, nil, fmt.Errorf("expect_hex: %w", err)
+		}
+	}
+	return payload, prefix, nil
+}
+
+// exchangeUDP отправляет датаграмму и ждет первый ответ до отмены ctx
+func exchangeUDP(ctx context.Context, d *dialer, address string, payload []byte) ([]byte, error) {
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil, fmt.Errorf("нет ответа от %s", address)
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// validateUDP проверяет hex данные UDP проверки
+func validateUDP(cfg models.ProbeConfig) error {
+	if cfg.UDP == nil {
+		return nil
+	}
+	_, _, err := udpPayloads(*cfg.UDP)
+	return err
+}
@@ -0,0 +1,326 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+	"service-monitor/internal/notify"
+)
+
+// dispatchInterval период проверки алертов, ожидающих оповещения по маршрутам
+const dispatchInterval = 10 * time.Second
+
+// Dispatcher оповещает каналы маршрутов, выбранных деревом маршрутизации:
+// об активных алертах после group_wait и повторно через repeat_interval,
+// о разрешении - те же каналы, что получили срабатывание
+type Dispatcher struct {
+	db       *database.DB
+	notifier *notify.Notifier
+	logger   *logger.Logger
+}
+
+func NewDispatcher(db *database.DB, notifier *notify.Notifier, logger *logger.Logger) *Dispatcher {
+	return &Dispatcher{
+		db:       db,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// routeState когда маршрут последний раз оповещал об алерте
+type routeState struct {
+	alertID int
+	path    string
+}
+
+// Run рассылает оповещения каждые 10 секунд до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchFiring()
+		d.dispatchResolved()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchFiring() {
+	root, err := Load(d.db)
+	if err != nil {
+		d.logger.Error("Ошибка загрузки маршрутов:", err)
+		return
+	}
+	if len(root.Channels) == 0 && len(root.Routes) == 0 {
+		return
+	}
+
+	alerts, err := d.activeAlerts()
+	if err != nil {
+		d.logger.Error("Ошибка получения алертов:", err)
+		return
+	}
+
+	services, err := d.services()
+	if err != nil {
+		d.logger.Error("Ошибка получения сервисов:", err)
+		return
+	}
+
+	notified, err := d.notified()
+	if err != nil {
+		d.logger.Error("Ошибка получения состояния маршрутов:", err)
+		return
+	}
+
+	// Алерты, попавшие в маршрут, собираются в группу: первое оповещение
+	// уходит одним сообщением через group_wait после самого раннего из них
+	var order []string
+	groups := make(map[string]*routeGroup)
+	now := time.Now()
+	for _, alert := range alerts {
+		labels := AlertLabels(alert, services[alert.ServiceID])
+
+		for _, matched := range Match(root, labels) {
+			route := matched.Route
+			if len(route.Channels) == 0 {
+				continue
+			}
+
+			group, ok := groups[matched.Path]
+			if !ok {
+				group = &routeGroup{path: matched.Path, route: route}
+				groups[matched.Path] = group
+				order = append(order, matched.Path)
+			}
+
+			last, ok := notified[routeState{alertID: alert.ID, path: matched.Path}]
+			switch {
+			case !ok:
+				group.first = append(group.first, alert)
+			case route.RepeatIntervalSeconds > 0 && now.Sub(last) >= time.Duration(route.RepeatIntervalSeconds)*time.Second:
+				group.repeat = append(group.repeat, alert)
+				group.last = append(group.last, last)
+			}
+		}
+	}
+
+	for _, path := range order {
+		d.notifyFirst(groups[path], now)
+		d.notifyRepeat(groups[path], now)
+	}
+}
+
+// routeGroup алерты маршрута, ожидающие первого или повторного оповещения
+type routeGroup struct {
+	path   string
+	route  models.Route
+	first  []models.Alert
+	repeat []models.Alert
+	last   []time.Time // время предыдущего оповещения для repeat
+}
+
+// notifyFirst оповещает группу, когда с открытия самого раннего алерта
+// прошло group_wait; алерт, разрешившийся раньше, в группу не попадет
+func (d *Dispatcher) notifyFirst(group *routeGroup, now time.Time) {
+	if len(group.first) == 0 {
+		return
+	}
+
+	oldest := group.first[0].CreatedAt
+	for _, alert := range group.first {
+		if alert.CreatedAt.Before(oldest) {
+			oldest = alert.CreatedAt
+		}
+	}
+	if now.Sub(oldest) < time.Duration(group.route.GroupWaitSeconds)*time.Second {
+		return
+	}
+
+	var claimed []models.Alert
+	for _, alert := range group.first {
+		if d.claim(`
+			INSERT INTO route_notifications (alert_id, route_path, channels, last_notified_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, alert.ID, group.path, pq.Array(group.route.Channels), now) {
+			claimed = append(claimed, alert)
+		}
+	}
+	d.enqueue(group.route.Channels, notify.EventFiring, claimed)
+}
+
+// notifyRepeat повторно оповещает об алертах, у которых истек repeat_interval
+func (d *Dispatcher) notifyRepeat(group *routeGroup, now time.Time) {
+	var claimed []models.Alert
+	for i, alert := range group.repeat {
+		if d.claim(`
+			UPDATE route_notifications SET last_notified_at = $3, channels = $4
+			WHERE alert_id = $1 AND route_path = $2 AND last_notified_at = $5
+		`, alert.ID, group.path, now, pq.Array(group.route.Channels), group.last[i]) {
+			claimed = append(claimed, alert)
+		}
+	}
+	d.enqueue(group.route.Channels, notify.EventFiring, claimed)
+}
+
+// dispatchResolved оповещает о разрешении каналы, получившие срабатывание
+func (d *Dispatcher) dispatchResolved() {
+	query := `
+		UPDATE route_notifications rn SET resolve_notified = true
+		FROM alerts a
+		WHERE a.id = rn.alert_id AND a.is_resolved = true AND rn.resolve_notified = false
+		RETURNING rn.alert_id, rn.channels
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		d.logger.Error("Ошибка получения разрешенных алертов:", err)
+		return
+	}
+
+	channels := make(map[int][]int)
+	for rows.Next() {
+		var alertID int
+		var ids []int64
+		if err := rows.Scan(&alertID, pq.Array(&ids)); err != nil {
+			d.logger.Error("Ошибка чтения состояния маршрута:", err)
+			rows.Close()
+			return
+		}
+		for _, id := range ids {
+			channels[alertID] = appendUnique(channels[alertID], int(id))
+		}
+	}
+	rows.Close()
+
+	for alertID, ids := range channels {
+		var alert models.Alert
+		err := alerting.ScanAlert(d.db.QueryRow(`SELECT `+alerting.AlertColumns+` FROM alerts WHERE id = $1`, alertID), &alert)
+		if err != nil {
+			d.logger.Error("Ошибка получения алерта:", err)
+			continue
+		}
+		d.enqueue(ids, notify.EventResolved, []models.Alert{alert})
+	}
+}
+
+// claim выполняет изменение состояния и сообщает, изменилась ли строка:
+// оповещение отправляется только тем, кто его изменил
+func (d *Dispatcher) claim(query string, args ...interface{}) bool {
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		d.logger.Error("Ошибка обновления состояния маршрута:", err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// enqueue ставит оповещение об алертах в каналы: несколько алертов
+// уходят одним групповым сообщением
+func (d *Dispatcher) enqueue(channels []int, event string, alerts []models.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	for _, channelID := range channels {
+		if err := d.notifier.EnqueueGroup(channelID, event, alerts); err != nil {
+			d.logger.Errorf("Ошибка оповещения по алерту %d: %v", alerts[0].ID, err)
+		}
+	}
+}
+
+// activeAlerts активные алерты, которые оповещает дерево маршрутов: алерты
+// с каналами правила или политикой эскалации сервиса оповещают они
+// (см. alerting.ByRuleChannels), иначе общий канал получил бы оповещение дважды
+func (d *Dispatcher) activeAlerts() ([]models.Alert, error) {
+	query := `
+		SELECT ` + alerting.AlertColumns + `
+		FROM alerts a
+		WHERE a.is_resolved = false
+		  AND NOT ` + alerting.ByRuleChannels + `
+		  AND NOT ` + alerting.ByEscalation + `
+		ORDER BY a.id
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		if err := alerting.ScanAlert(rows, &alert); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (d *Dispatcher) services() (map[int]*models.Service, error) {
+	rows, err := d.db.Query(`SELECT ` + database.ServiceColumns + ` FROM services`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := make(map[int]*models.Service)
+	for rows.Next() {
+		var service models.Service
+		if err := database.ScanService(rows, &service); err != nil {
+			return nil, err
+		}
+		services[service.ID] = &service
+	}
+	return services, rows.Err()
+}
+
+// notified время последнего оповещения по маршрутам активных алертов
+func (d *Dispatcher) notified() (map[routeState]time.Time, error) {
+	query := `
+		SELECT rn.alert_id, rn.route_path, rn.last_notified_at
+		FROM route_notifications rn
+		JOIN alerts a ON a.id = rn.alert_id
+		WHERE a.is_resolved = false
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notified := make(map[routeState]time.Time)
+	for rows.Next() {
+		var key routeState
+		var last time.Time
+		if err := rows.Scan(&key.alertID, &key.path, &last); err != nil {
+			return nil, err
+		}
+		notified[key] = last
+	}
+	return notified, rows.Err()
+}
+
+func appendUnique(ids []int, id int) []int {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
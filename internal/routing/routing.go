@@ -0,0 +1,183 @@
+package routing
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+)
+
+// MatcherOperator операторы условий маршрута
+const (
+	OpEqual     = "="
+	OpNotEqual  = "!="
+	OpRegexp    = "=~"
+	OpNotRegexp = "!~"
+)
+
+// Load загружает дерево маршрутизации; без сохраненного дерева это пустой корень
+func Load(db *database.DB) (models.Route, error) {
+	var root models.Route
+	err := db.QueryRow(`SELECT tree FROM routing_tree WHERE id = 1`).Scan(&root)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Route{}, nil
+	}
+	return root, err
+}
+
+// Save сохраняет дерево маршрутизации целиком
+func Save(db *database.DB, root models.Route) error {
+	query := `
+		INSERT INTO routing_tree (id, tree, updated_at)
+		VALUES (1, $1, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET tree = EXCLUDED.tree, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := db.Exec(query, root)
+	return err
+}
+
+// Validate проверяет операторы и регулярные выражения всех маршрутов
+func Validate(route models.Route) error {
+	return validate(route, "")
+}
+
+func validate(route models.Route, path string) error {
+	name := path
+	if name == "" {
+		name = "root"
+	}
+
+	for _, m := range route.Matchers {
+		switch m.Op {
+		case OpEqual, OpNotEqual:
+		case OpRegexp, OpNotRegexp:
+			if _, err := compile(m.Value); err != nil {
+				return fmt.Errorf("маршрут %s: неверное выражение %q: %w", name, m.Value, err)
+			}
+		default:
+			return fmt.Errorf("маршрут %s: неверный оператор %q", name, m.Op)
+		}
+		if m.Label == "" {
+			return fmt.Errorf("маршрут %s: не указана метка", name)
+		}
+	}
+	if route.GroupWaitSeconds < 0 || route.RepeatIntervalSeconds < 0 {
+		return fmt.Errorf("маршрут %s: интервалы не могут быть отрицательными", name)
+	}
+
+	for i, child := range route.Routes {
+		if err := validate(child, childPath(path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AlertLabels метки алерта для маршрутизации: метки сервиса и алерта, а также
+// service, group, severity и kind
+func AlertLabels(alert models.Alert, service *models.Service) models.Labels {
+	labels := models.Labels{}
+	if service != nil {
+		for k, v := range service.Labels {
+			labels[k] = v
+		}
+		labels["service"] = service.Name
+		labels["group"] = service.Group
+	}
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	labels["severity"] = alert.Severity
+	labels["kind"] = alert.Kind
+	return labels
+}
+
+// Match возвращает маршруты, которые получает алерт с метками labels
+func Match(root models.Route, labels models.Labels) []models.MatchedRoute {
+	return match(root, labels, "")
+}
+
+func match(route models.Route, labels models.Labels, path string) []models.MatchedRoute {
+	var matched []models.MatchedRoute
+	for i, child := range route.Routes {
+		if !matches(child.Matchers, labels) {
+			continue
+		}
+		// Маршрут без своих каналов оповещает каналы родителя
+		if len(child.Channels) == 0 {
+			child.Channels = route.Channels
+		}
+
+		matched = append(matched, match(child, labels, childPath(path, i))...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(matched) == 0 {
+		if path == "" {
+			path = "root"
+		}
+		// Дочерние маршруты не нужны в ответе: алерт их уже не получает
+		route.Routes = nil
+		matched = append(matched, models.MatchedRoute{Path: path, Route: route})
+	}
+	return matched
+}
+
+// Channels каналы выбранных маршрутов без повторов
+func Channels(routes []models.MatchedRoute) []int {
+	channels := []int{}
+	seen := make(map[int]bool)
+	for _, r := range routes {
+		for _, id := range r.Route.Channels {
+			if !seen[id] {
+				seen[id] = true
+				channels = append(channels, id)
+			}
+		}
+	}
+	return channels
+}
+
+func matches(matchers []models.Matcher, labels models.Labels) bool {
+	for _, m := range matchers {
+		value := labels[m.Label]
+
+		var ok bool
+		switch m.Op {
+		case OpEqual:
+			ok = value == m.Value
+		case OpNotEqual:
+			ok = value != m.Value
+		case OpRegexp, OpNotRegexp:
+			re, err := compile(m.Value)
+			if err != nil {
+				return false
+			}
+			ok = re.MatchString(value) == (m.Op == OpRegexp)
+		}
+
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compile регулярное выражение должно совпасть со значением целиком
+func compile(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+func childPath(path string, i int) string {
+	if path == "" {
+		return strconv.Itoa(i)
+	}
+	return path + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/models"
+)
+
+func testTree() models.Route {
+	return models.Route{
+		Channels: []int{1},
+		Routes: []models.Route{
+			{
+				Matchers: []models.Matcher{{Label: "team", Op: OpEqual, Value: "payments"}},
+				Channels: []int{2},
+				Continue: true,
+				Routes: []models.Route{
+					{
+						Matchers: []models.Matcher{{Label: "severity", Op: OpEqual, Value: "critical"}},
+						Channels: []int{3},
+					},
+				},
+			},
+			{
+				Matchers: []models.Matcher{{Label: "service", Op: OpRegexp, Value: "api-.*"}},
+				Channels: []int{4, 2},
+			},
+			{
+				Matchers: []models.Matcher{{Label: "severity", Op: OpNotEqual, Value: "info"}},
+				Channels: []int{5},
+			},
+		},
+	}
+}
+
+func paths(routes []models.MatchedRoute) []string {
+	var result []string
+	for _, r := range routes {
+		result = append(result, r.Path)
+	}
+	return result
+}
+
+func TestMatch(t *testing.T) {
+	root := testTree()
+
+	// continue: после команды проверяется следующий маршрут, на нем спуск останавливается
+	matched := Match(root, models.Labels{"team": "payments", "severity": "critical", "service": "api-gw"})
+	assert.Equal(t, []string{"0.0", "1"}, paths(matched))
+	assert.Equal(t, []int{3, 4, 2}, Channels(matched))
+
+	// Дочерний маршрут не подошел - алерт получает сам узел 0
+	matched = Match(root, models.Labels{"team": "payments", "severity": "warning", "service": "web"})
+	assert.Equal(t, []string{"0", "2"}, paths(matched))
+	assert.Nil(t, matched[0].Route.Routes)
+
+	// Регулярное выражение должно совпасть целиком
+	matched = Match(root, models.Labels{"service": "my-api-gw", "severity": "info"})
+	assert.Equal(t, []string{"root"}, paths(matched))
+	assert.Equal(t, []int{1}, Channels(matched))
+
+	matched = Match(root, models.Labels{"service": "api-gw", "severity": "info"})
+	assert.Equal(t, []string{"1"}, paths(matched))
+}
+
+func TestMatchInheritsChannels(t *testing.T) {
+	root := models.Route{
+		Channels: []int{1},
+		Routes: []models.Route{
+			{
+				Matchers: []models.Matcher{{Label: "team", Op: OpEqual, Value: "payments"}},
+				Channels: []int{2},
+				Routes: []models.Route{
+					// Только меняет интервалы - каналы берутся у родителя
+					{Matchers: []models.Matcher{{Label: "severity", Op: OpEqual, Value: "critical"}}, RepeatIntervalSeconds: 600},
+				},
+			},
+			{Matchers: []models.Matcher{{Label: "team", Op: OpEqual, Value: "web"}}},
+		},
+	}
+
+	matched := Match(root, models.Labels{"team": "payments", "severity": "critical"})
+	assert.Equal(t, []string{"0.0"}, paths(matched))
+	assert.Equal(t, []int{2}, Channels(matched))
+
+	matched = Match(root, models.Labels{"team": "web"})
+	assert.Equal(t, []string{"1"}, paths(matched))
+	assert.Equal(t, []int{1}, Channels(matched))
+}
+
+func TestMatchEmptyTree(t *testing.T) {
+	matched := Match(models.Route{}, models.Labels{"severity": "critical"})
+	assert.Equal(t, []string{"root"}, paths(matched))
+	assert.Equal(t, []int{}, Channels(matched))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(testTree()))
+
+	tree := testTree()
+	tree.Routes[0].Routes[0].Matchers[0].Op = "=="
+	assert.EqualError(t, Validate(tree), `маршрут 0.0: неверный оператор "=="`)
+
+	tree = testTree()
+	tree.Routes[1].Matchers[0].Value = "api-("
+	assert.ErrorContains(t, Validate(tree), "маршрут 1: неверное выражение")
+
+	tree = testTree()
+	tree.GroupWaitSeconds = -1
+	assert.EqualError(t, Validate(tree), "маршрут root: интервалы не могут быть отрицательными")
+}
+
+func TestAlertLabels(t *testing.T) {
+	service := &models.Service{Name: "api", Group: "core", Labels: models.Labels{"team": "payments", "severity": "low"}}
+	alert := models.Alert{Severity: "critical", Kind: "down", Labels: models.Labels{"team": "platform"}}
+
+	labels := AlertLabels(alert, service)
+	assert.Equal(t, models.Labels{
+		"team":     "platform",
+		"service":  "api",
+		"group":    "core",
+		"severity": "critical",
+		"kind":     "down",
+	}, labels)
+}
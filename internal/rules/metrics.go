@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/uptime"
+)
+
+// checkPoint проверка сервиса, нужная для расчета метрик
+type checkPoint struct {
+	At           time.Time
+	Status       string // статус самой проверки
+	Up           bool   // итоговый статус сервиса не unhealthy
+	ResponseTime int
+}
+
+// series проверки сервиса, отсортированные по времени, и окна обслуживания
+type series struct {
+	checks      []checkPoint
+	maintenance []uptime.Interval
+	createdAt   time.Time // с этого момента считается возраст, пока проверок нет
+	maxGap      time.Duration
+}
+
+// between проверки в [from, to)
+func (s series) between(from, to time.Time) []checkPoint {
+	start := sort.Search(len(s.checks), func(i int) bool { return !s.checks[i].At.Before(from) })
+	end := sort.Search(len(s.checks), func(i int) bool { return !s.checks[i].At.Before(to) })
+	return s.checks[start:end]
+}
+
+// value считает метрику в момент at по окну window. false - данных нет,
+// условие в этом случае не выполняется
+func (s series) value(metric string, window time.Duration, at time.Time) (float64, bool) {
+	from := at.Add(-window)
+
+	switch metric {
+	case models.MetricLastCheckAge:
+		last := s.createdAt
+		if checks := s.between(time.Time{}, at.Add(time.Nanosecond)); len(checks) > 0 {
+			last = checks[len(checks)-1].At
+		}
+		if at.Before(last) {
+			return 0, true
+		}
+		return at.Sub(last).Seconds(), true
+
+	case models.MetricUptime:
+		checks := s.between(from.Add(-s.maxGap), at)
+		samples := make([]uptime.Sample, len(checks))
+		for i, c := range checks {
+			samples[i] = uptime.Sample{At: c.At, Up: c.Up}
+		}
+		result := uptime.Calculate(from, at, samples, s.maintenance, s.maxGap)
+		if result.UpSeconds+result.DownSeconds == 0 {
+			return 0, false
+		}
+		return result.Uptime, true
+	}
+
+	checks := s.between(from, at)
+	if len(checks) == 0 {
+		return 0, false
+	}
+
+	switch metric {
+	case models.MetricP95Latency:
+		times := make([]int, len(checks))
+		for i, c := range checks {
+			times[i] = c.ResponseTime
+		}
+		sort.Ints(times)
+		rank := int(math.Ceil(0.95*float64(len(times)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return float64(times[rank]), true
+
+	case models.MetricAvgLatency:
+		var sum int
+		for _, c := range checks {
+			sum += c.ResponseTime
+		}
+		return float64(sum) / float64(len(checks)), true
+
+	case models.MetricErrorRate:
+		var failed int
+		for _, c := range checks {
+			if c.Status == models.StatusUnhealthy {
+				failed++
+			}
+		}
+		return float64(failed) * 100 / float64(len(checks)), true
+	}
+
+	return 0, false
+}
+
+// compare проверяет условие правила
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// validOperator сообщает, поддерживается ли оператор
+func validOperator(operator string) bool {
+	switch operator {
+	case ">", ">=", "<", "<=":
+		return true
+	}
+	return false
+}
+
+// simulate по последовательным значениям условия определяет периоды, когда
+// алерт был бы открыт: условие должно выполняться не меньше hold подряд
+func simulate(points []models.RulePreviewPoint, hold time.Duration) []models.FiringPeriod {
+	periods := []models.FiringPeriod{}
+
+	var pendingSince *time.Time
+	firing := false
+	for _, p := range points {
+		if !p.Matches {
+			if firing {
+				end := p.Time
+				periods[len(periods)-1].End = &end
+			}
+			pendingSince = nil
+			firing = false
+			continue
+		}
+
+		if pendingSince == nil {
+			t := p.Time
+			pendingSince = &t
+		}
+		if !firing && p.Time.Sub(*pendingSince) >= hold {
+			firing = true
+			periods = append(periods, models.FiringPeriod{Start: p.Time})
+		}
+	}
+
+	return periods
+}
@@ -0,0 +1,383 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+	"service-monitor/internal/notify"
+	"service-monitor/internal/uptime"
+)
+
+// evaluateInterval период проверки правил
+const evaluateInterval = time.Minute
+
+// MaxPreviewPoints ограничение на количество точек предпросмотра для одного сервиса
+const MaxPreviewPoints = 1000
+
+// Evaluator периодически проверяет пользовательские правила, открывает и
+// разрешает их алерты и оповещает каналы правила
+type Evaluator struct {
+	db       *database.DB
+	alerts   *alerting.Manager
+	notifier *notify.Notifier
+	uptime   *uptime.Calculator
+	logger   *logger.Logger
+
+	// pending с какого момента условие выполняется, по отпечатку алерта.
+	// Хранится в памяти лидера: после смены лидера отсчет начинается заново
+	pending map[string]time.Time
+}
+
+func NewEvaluator(db *database.DB, alerts *alerting.Manager, notifier *notify.Notifier, logger *logger.Logger) *Evaluator {
+	return &Evaluator{
+		db:       db,
+		alerts:   alerts,
+		notifier: notifier,
+		uptime:   uptime.NewCalculator(db),
+		logger:   logger,
+		pending:  make(map[string]time.Time),
+	}
+}
+
+// RuleColumns список колонок alert_rules в порядке, который ожидает ScanRule
+const RuleColumns = `id, name, service_id, group_name, metric, operator, threshold, window_seconds, for_seconds,
+	severity, labels, channels, enabled, created_at`
+
+// ScanRule читает строку, выбранную через RuleColumns
+func ScanRule(row database.Scanner, rule *models.AlertRule) error {
+	var channels []int64
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.ServiceID,
+		&rule.Group,
+		&rule.Metric,
+		&rule.Operator,
+		&rule.Threshold,
+		&rule.WindowSeconds,
+		&rule.ForSeconds,
+		&rule.Severity,
+		&rule.Labels,
+		pq.Array(&channels),
+		&rule.Enabled,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rule.Channels = make([]int, len(channels))
+	for i, id := range channels {
+		rule.Channels[i] = int(id)
+	}
+	return nil
+}
+
+// RuleFingerprint отпечаток алерта правила для сервиса
+func RuleFingerprint(ruleID, serviceID int) string {
+	return fmt.Sprintf("rule:%d:service:%d", ruleID, serviceID)
+}
+
+// Validate проверяет поля правила, которые не проверяет binding
+func Validate(rule models.AlertRule) error {
+	if !validOperator(rule.Operator) {
+		return fmt.Errorf("неверный operator %q: допустимы >, >=, <, <=", rule.Operator)
+	}
+	if rule.ServiceID != nil && rule.Group != "" {
+		return fmt.Errorf("укажите service_id или group, но не оба")
+	}
+	if rule.Metric != models.MetricLastCheckAge && rule.WindowSeconds <= 0 {
+		return fmt.Errorf("window_seconds должен быть положительным")
+	}
+	if rule.ForSeconds < 0 {
+		return fmt.Errorf("for_seconds не может быть отрицательным")
+	}
+	return nil
+}
+
+// Run проверяет правила каждую минуту до отмены ctx
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(evaluateInterval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluateAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Evaluator) evaluateAll() {
+	rows, err := e.db.Query(`SELECT ` + RuleColumns + ` FROM alert_rules`)
+	if err != nil {
+		e.logger.Error("Ошибка получения правил:", err)
+		return
+	}
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		var rule models.AlertRule
+		if err := ScanRule(rows, &rule); err != nil {
+			e.logger.Error("Ошибка чтения правила:", err)
+			rows.Close()
+			return
+		}
+		rules = append(rules, rule)
+	}
+	rows.Close()
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if err := e.evaluate(rule, now, seen); err != nil {
+			e.logger.Errorf("Ошибка проверки правила %s: %v", rule.Name, err)
+		}
+	}
+
+	// Удаленные правила и сервисы больше не проверяются
+	for fingerprint := range e.pending {
+		if !seen[fingerprint] {
+			delete(e.pending, fingerprint)
+		}
+	}
+}
+
+// evaluate проверяет правило для каждого подходящего сервиса. Алерты
+// сервисов, которые больше не подходят под правило, разрешаются
+func (e *Evaluator) evaluate(rule models.AlertRule, now time.Time, seen map[string]bool) error {
+	var services []models.Service
+	if rule.Enabled {
+		var err error
+		if services, err = e.targets(rule); err != nil {
+			return err
+		}
+	}
+
+	active := make(map[string]bool)
+	for _, service := range services {
+		fingerprint := RuleFingerprint(rule.ID, service.ID)
+		active[fingerprint] = true
+		seen[fingerprint] = true
+
+		s, err := e.load(service, rule, now, now)
+		if err != nil {
+			return err
+		}
+
+		value, ok := s.value(rule.Metric, time.Duration(rule.WindowSeconds)*time.Second, now)
+		if !ok || !compare(value, rule.Operator, rule.Threshold) {
+			delete(e.pending, fingerprint)
+			e.resolve(rule, fingerprint)
+			continue
+		}
+
+		since, ok := e.pending[fingerprint]
+		if !ok {
+			since = now
+			e.pending[fingerprint] = now
+		}
+		if now.Sub(since) < time.Duration(rule.ForSeconds)*time.Second {
+			continue
+		}
+
+		e.fire(rule, service, fingerprint, value)
+	}
+
+	// Сервис удален из группы, правило выключено или изменено
+	var stale []string
+	rows, err := e.db.Query(`SELECT fingerprint FROM alerts WHERE rule_id = $1 AND is_resolved = false`, rule.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var fingerprint string
+		if err := rows.Scan(&fingerprint); err != nil {
+			rows.Close()
+			return err
+		}
+		if !active[fingerprint] {
+			stale = append(stale, fingerprint)
+		}
+	}
+	rows.Close()
+
+	for _, fingerprint := range stale {
+		e.resolve(rule, fingerprint)
+	}
+	return nil
+}
+
+func (e *Evaluator) fire(rule models.AlertRule, service models.Service, fingerprint string, value float64) {
+	ruleID := rule.ID
+	alert := models.Alert{
+		ServiceID: service.ID,
+		RuleID:    &ruleID,
+		Message: fmt.Sprintf("Правило %s: %s = %.2f (%s %g) для сервиса %s",
+			rule.Name, rule.Metric, value, rule.Operator, rule.Threshold, service.Name),
+		Severity:    rule.Severity,
+		Kind:        models.AlertKindRule,
+		Fingerprint: fingerprint,
+		Labels:      rule.Labels,
+		CreatedAt:   time.Now(),
+	}
+
+	id, err := e.alerts.Fire(alert)
+	if err != nil {
+		e.logger.Error("Ошибка создания алерта:", err)
+		return
+	}
+	if id == 0 {
+		return
+	}
+
+	alert.ID = id
+	e.notify(rule, notify.EventFiring, alert)
+}
+
+func (e *Evaluator) resolve(rule models.AlertRule, fingerprint string) {
+	resolved, err := e.alerts.Resolve(fingerprint)
+	if err != nil {
+		e.logger.Error("Ошибка разрешения алертов:", err)
+		return
+	}
+
+	for _, alert := range resolved {
+		e.notify(rule, notify.EventResolved, alert)
+	}
+}
+
+func (e *Evaluator) notify(rule models.AlertRule, event string, alert models.Alert) {
+	for _, channelID := range rule.Channels {
+		if err := e.notifier.Enqueue(channelID, "", event, alert); err != nil {
+			e.logger.Errorf("Ошибка оповещения по алерту %d: %v", alert.ID, err)
+		}
+	}
+}
+
+// targets сервисы, к которым относится правило
+func (e *Evaluator) targets(rule models.AlertRule) ([]models.Service, error) {
+	query := `SELECT ` + database.ServiceColumns + ` FROM services`
+	var args []interface{}
+
+	switch {
+	case rule.ServiceID != nil:
+		query += ` WHERE id = $1`
+		args = append(args, *rule.ServiceID)
+	case rule.Group != "":
+		query += ` WHERE group_name = $1`
+		args = append(args, rule.Group)
+	}
+
+	rows, err := e.db.Query(query+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []models.Service
+	for rows.Next() {
+		var service models.Service
+		if err := database.ScanService(rows, &service); err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, rows.Err()
+}
+
+// load загружает проверки сервиса, нужные для расчета метрики правила
+// в моменты от from до to
+func (e *Evaluator) load(service models.Service, rule models.AlertRule, from, to time.Time) (series, error) {
+	window := time.Duration(rule.WindowSeconds) * time.Second
+	s := series{createdAt: service.CreatedAt, maxGap: uptime.MaxGap(service)}
+
+	start := from.Add(-window)
+	switch rule.Metric {
+	case models.MetricUptime:
+		start = start.Add(-s.maxGap)
+	case models.MetricLastCheckAge:
+		// Достаточно последней проверки перед началом периода
+		var last time.Time
+		err := e.db.QueryRow(`SELECT COALESCE(MAX(checked_at), $2) FROM health_checks WHERE service_id = $1 AND checked_at < $2`,
+			service.ID, from).Scan(&last)
+		if err != nil {
+			return s, err
+		}
+		start = last
+	}
+
+	query := `
+		SELECT checked_at, status, COALESCE(service_status, status) <> $4, COALESCE(response_time, 0)
+		FROM health_checks
+		WHERE service_id = $1 AND checked_at >= $2 AND checked_at < $3
+		ORDER BY checked_at
+	`
+
+	rows, err := e.db.Query(query, service.ID, start, to, models.StatusUnhealthy)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c checkPoint
+		if err := rows.Scan(&c.At, &c.Status, &c.Up, &c.ResponseTime); err != nil {
+			return s, err
+		}
+		s.checks = append(s.checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	if rule.Metric == models.MetricUptime {
+		s.maintenance, err = e.uptime.Maintenance(service.ID, start, to)
+	}
+	return s, err
+}
+
+// Preview вычисляет правило на исторических данных за [from, to) с шагом step
+// и показывает, когда его алерт был бы открыт для каждого сервиса
+func (e *Evaluator) Preview(rule models.AlertRule, from, to time.Time, step time.Duration) ([]models.RulePreview, error) {
+	services, err := e.targets(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(rule.WindowSeconds) * time.Second
+	previews := []models.RulePreview{}
+	for _, service := range services {
+		s, err := e.load(service, rule, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		preview := models.RulePreview{ServiceID: service.ID, ServiceName: service.Name}
+		for t := from; t.Before(to); t = t.Add(step) {
+			point := models.RulePreviewPoint{Time: t}
+			if value, ok := s.value(rule.Metric, window, t); ok {
+				v := value
+				point.Value = &v
+				point.Matches = compare(value, rule.Operator, rule.Threshold)
+			}
+			preview.Points = append(preview.Points, point)
+		}
+		preview.Firing = simulate(preview.Points, time.Duration(rule.ForSeconds)*time.Second)
+
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
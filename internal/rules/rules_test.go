@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/models"
+	"service-monitor/internal/uptime"
+)
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+func testSeries() series {
+	var checks []checkPoint
+	for m := 0; m < 20; m++ {
+		status := models.StatusHealthy
+		if m >= 10 {
+			status = models.StatusUnhealthy
+		}
+		checks = append(checks, checkPoint{
+			At:           at(m),
+			Status:       status,
+			Up:           status != models.StatusUnhealthy,
+			ResponseTime: (m + 1) * 10,
+		})
+	}
+	return series{checks: checks, createdAt: at(-60), maxGap: 3 * time.Minute}
+}
+
+func TestSeriesLatency(t *testing.T) {
+	s := testSeries()
+
+	// Окно [0, 10): 10..100 мс
+	v, ok := s.value(models.MetricP95Latency, 10*time.Minute, at(10))
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, v)
+
+	v, ok = s.value(models.MetricAvgLatency, 10*time.Minute, at(10))
+	assert.True(t, ok)
+	assert.Equal(t, 55.0, v)
+
+	_, ok = s.value(models.MetricAvgLatency, 10*time.Minute, at(-5))
+	assert.False(t, ok)
+}
+
+func TestSeriesErrorRate(t *testing.T) {
+	s := testSeries()
+
+	v, ok := s.value(models.MetricErrorRate, 10*time.Minute, at(15))
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, v)
+}
+
+func TestSeriesUptime(t *testing.T) {
+	s := testSeries()
+
+	v, ok := s.value(models.MetricUptime, 20*time.Minute, at(20))
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, v)
+
+	// Обслуживание исключает время сбоя
+	s.maintenance = []uptime.Interval{{Start: at(10), End: at(20)}}
+	v, ok = s.value(models.MetricUptime, 20*time.Minute, at(20))
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, v)
+}
+
+func TestSeriesLastCheckAge(t *testing.T) {
+	s := testSeries()
+
+	v, ok := s.value(models.MetricLastCheckAge, 0, at(25))
+	assert.True(t, ok)
+	assert.Equal(t, 360.0, v)
+
+	// Пока проверок нет, возраст считается от создания сервиса
+	v, _ = s.value(models.MetricLastCheckAge, 0, at(-30))
+	assert.Equal(t, 1800.0, v)
+}
+
+func TestCompare(t *testing.T) {
+	assert.True(t, compare(800.5, ">", 800))
+	assert.False(t, compare(800, ">", 800))
+	assert.True(t, compare(800, ">=", 800))
+	assert.True(t, compare(98.9, "<", 99))
+	assert.True(t, compare(99, "<=", 99))
+	assert.False(t, compare(1, "==", 1))
+	assert.False(t, validOperator("=="))
+}
+
+func TestSimulate(t *testing.T) {
+	var points []models.RulePreviewPoint
+	for m, match := range []bool{false, true, true, true, false, true, true, true, true} {
+		points = append(points, models.RulePreviewPoint{Time: at(m), Matches: match})
+	}
+
+	// Условие должно держаться 2 минуты: первый эпизод открывается на 3-й
+	// минуте и закрывается на 4-й, второй открывается на 7-й и не закрыт
+	periods := simulate(points, 2*time.Minute)
+	if assert.Len(t, periods, 2) {
+		assert.Equal(t, at(3), periods[0].Start)
+		assert.Equal(t, at(4), *periods[0].End)
+		assert.Equal(t, at(7), periods[1].Start)
+		assert.Nil(t, periods[1].End)
+	}
+
+	assert.Len(t, simulate(points, 10*time.Minute), 0)
+	assert.Len(t, simulate(points, 0), 2)
+}
@@ -0,0 +1,197 @@
+package secrets
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"service-monitor/internal/models"
+)
+
+// Mask заменяет значения учетных данных, заданные в настройках открытым
+// текстом, в ответах API. При изменении маска означает "оставить как есть"
+const Mask = "********"
+
+// mysqlDSN пароль в строке подключения MySQL
+var mysqlDSN = regexp.MustCompile(`^[^:/@]*:(.*)@[a-z0-9]*\(`)
+
+// keywordPassword пароль в строке подключения Postgres вида
+// host=db user=monitor password='p@ss word'
+var keywordPassword = regexp.MustCompile(`(?:^|\s)password\s*=\s*('(?:[^'\\]|\\.)*'|[^\s']+)`)
+
+// sensitiveHeaders заголовки, значения которых скрываются
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// RedactService скрывает учетные данные сервиса. Ссылки на секреты
+// не скрываются: в них нет значений
+func RedactService(service models.Service) models.Service {
+	service.URL = RedactURL(service.URL)
+	service.ProbeConfig = RedactProbe(service.ProbeConfig)
+	return service
+}
+
+// RedactProbe скрывает учетные данные в настройках проверки
+func RedactProbe(cfg models.ProbeConfig) models.ProbeConfig {
+	cfg.Headers = redactHeaders(cfg.Headers)
+
+	if cfg.Auth != nil {
+		auth := *cfg.Auth
+		auth.Password = redact(auth.Password)
+		auth.Token = redact(auth.Token)
+		if auth.OAuth2 != nil {
+			oauth := *auth.OAuth2
+			oauth.ClientSecret = redact(oauth.ClientSecret)
+			auth.OAuth2 = &oauth
+		}
+		cfg.Auth = &auth
+	}
+
+	if cfg.GRPC != nil {
+		grpc := *cfg.GRPC
+		grpc.Metadata = redactHeaders(grpc.Metadata)
+		cfg.GRPC = &grpc
+	}
+
+	if cfg.Network != nil {
+		network := *cfg.Network
+		network.Proxy = RedactURL(network.Proxy)
+		cfg.Network = &network
+	}
+
+	if cfg.TLS != nil {
+		tls := *cfg.TLS
+		tls.ClientKey = redact(tls.ClientKey)
+		cfg.TLS = &tls
+	}
+
+	if cfg.Scenario != nil {
+		scenario := models.ScenarioProbeConfig{Steps: make([]models.ScenarioStep, len(cfg.Scenario.Steps))}
+		for i, step := range cfg.Scenario.Steps {
+			step.URL = RedactURL(step.URL)
+			step.Headers = redactHeaders(step.Headers)
+			scenario.Steps[i] = step
+		}
+		cfg.Scenario = &scenario
+	}
+
+	return cfg
+}
+
+// RedactChannel скрывает учетные данные в настройках канала
+func RedactChannel(channel models.Channel) models.Channel {
+	channel.Config.URL = RedactURL(channel.Config.URL)
+	channel.Config.Headers = redactHeaders(channel.Config.Headers)
+	return channel
+}
+
+// RedactURL скрывает пароль в URL, строке подключения MySQL
+// (user:password@tcp(host:3306)/db) или Postgres вида key=value
+func RedactURL(raw string) string {
+	if m := mysqlDSN.FindStringSubmatchIndex(raw); m != nil {
+		return raw[:m[2]] + redact(raw[m[2]:m[3]]) + raw[m[3]:]
+	}
+	if m := keywordPassword.FindStringSubmatchIndex(raw); m != nil {
+		return raw[:m[2]] + redact(raw[m[2]:m[3]]) + raw[m[3]:]
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	password, ok := u.User.Password()
+	if !ok || redact(password) == password {
+		return raw
+	}
+	return strings.Replace(u.Redacted(), ":xxxxx@", ":"+Mask+"@", 1)
+}
+
+// RestoreProbe возвращает сохраненные значения вместо масок в измененных
+// настройках, чтобы клиент мог отправить обратно полученный от API объект
+func RestoreProbe(cfg *models.ProbeConfig, stored models.ProbeConfig) {
+	restoreHeaders(cfg.Headers, stored.Headers)
+
+	if cfg.Auth != nil && stored.Auth != nil {
+		restore(&cfg.Auth.Password, stored.Auth.Password)
+		restore(&cfg.Auth.Token, stored.Auth.Token)
+		if cfg.Auth.OAuth2 != nil && stored.Auth.OAuth2 != nil {
+			restore(&cfg.Auth.OAuth2.ClientSecret, stored.Auth.OAuth2.ClientSecret)
+		}
+	}
+
+	if cfg.GRPC != nil && stored.GRPC != nil {
+		restoreHeaders(cfg.GRPC.Metadata, stored.GRPC.Metadata)
+	}
+
+	if cfg.Network != nil && stored.Network != nil {
+		RestoreURL(&cfg.Network.Proxy, stored.Network.Proxy)
+	}
+
+	if cfg.TLS != nil && stored.TLS != nil {
+		restore(&cfg.TLS.ClientKey, stored.TLS.ClientKey)
+	}
+
+	if cfg.Scenario != nil && stored.Scenario != nil {
+		for i := range cfg.Scenario.Steps {
+			if i >= len(stored.Scenario.Steps) {
+				break
+			}
+			RestoreURL(&cfg.Scenario.Steps[i].URL, stored.Scenario.Steps[i].URL)
+			restoreHeaders(cfg.Scenario.Steps[i].Headers, stored.Scenario.Steps[i].Headers)
+		}
+	}
+}
+
+// RestoreChannel возвращает сохраненные значения вместо масок в настройках канала
+func RestoreChannel(cfg *models.ChannelConfig, stored models.ChannelConfig) {
+	RestoreURL(&cfg.URL, stored.URL)
+	restoreHeaders(cfg.Headers, stored.Headers)
+}
+
+// RestoreURL возвращает сохраненный URL, если пришел он же со скрытым паролем
+func RestoreURL(raw *string, stored string) {
+	if *raw != stored && *raw == RedactURL(stored) {
+		*raw = stored
+	}
+}
+
+func redact(value string) string {
+	if value == "" || reference.MatchString(value) {
+		return value
+	}
+	return Mask
+}
+
+func restore(value *string, stored string) {
+	if *value == Mask {
+		*value = stored
+	}
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			value = redact(value)
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func restoreHeaders(headers, stored map[string]string) {
+	for name, value := range headers {
+		if value == Mask {
+			headers[name] = stored[name]
+		}
+	}
+}
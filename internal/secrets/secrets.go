@@ -0,0 +1,364 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+)
+
+var (
+	// ErrDisabled хранилище работает только с ключом шифрования
+	ErrDisabled = errors.New("хранилище секретов выключено: не задан SECRETS_KEY")
+	// ErrNotFound секрета с таким именем нет
+	ErrNotFound = errors.New("секрет не найден")
+)
+
+// reference ссылка на секрет в настройках сервиса или канала: ${secret:имя}
+var reference = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.-]+)\}`)
+
+// validName допустимое имя секрета
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+// Store хранит секреты зашифрованными AES-256-GCM. Сервисы и каналы
+// ссылаются на секреты по имени, значения подставляются только перед
+// проверкой или отправкой оповещения
+type Store struct {
+	db   *database.DB
+	aead cipher.AEAD
+}
+
+// NewStore создает хранилище с мастер-ключом: 32 байта в base64.
+// С пустым ключом хранилище выключено, но настройки без ссылок работают
+func NewStore(db *database.DB, key string) (*Store, error) {
+	store := &Store{db: db}
+	if key == "" {
+		return store, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("SECRETS_KEY должен содержать 32 байта в base64")
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	if store.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// ValidateName проверяет имя секрета
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return errors.New("имя секрета может содержать только латинские буквы, цифры, _, . и - (до 100 символов)")
+	}
+	return nil
+}
+
+// Set создает или заменяет секрет
+func (s *Store) Set(name, value string) (models.Secret, error) {
+	secret := models.Secret{Name: name}
+	if s.aead == nil {
+		return secret, ErrDisabled
+	}
+
+	sealed, err := s.seal(name, value)
+	if err != nil {
+		return secret, err
+	}
+
+	query := `
+		INSERT INTO secrets (name, value)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at
+	`
+
+	err = s.db.QueryRow(query, name, sealed).Scan(&secret.CreatedAt, &secret.UpdatedAt)
+	return secret, err
+}
+
+// List возвращает имена секретов без значений
+func (s *Store) List() ([]models.Secret, error) {
+	rows, err := s.db.Query(`SELECT name, created_at, updated_at FROM secrets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	secrets := []models.Secret{}
+	for rows.Next() {
+		var secret models.Secret
+		if err := rows.Scan(&secret.Name, &secret.CreatedAt, &secret.UpdatedAt); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+// Delete удаляет секрет
+func (s *Store) Delete(name string) error {
+	result, err := s.db.Exec(`DELETE FROM secrets WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Usages перечисляет сервисы и каналы, которые ссылаются на секрет
+func (s *Store) Usages(name string) ([]string, error) {
+	query := `
+		SELECT 'сервис ' || name FROM services
+		WHERE strpos(url, $1) > 0 OR strpos(probe_config::text, $1) > 0
+		UNION ALL
+		SELECT 'канал ' || name FROM channels
+		WHERE strpos(config::text, $1) > 0
+	`
+
+	rows, err := s.db.Query(query, "${secret:"+name+"}")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []string
+	for rows.Next() {
+		var usage string
+		if err := rows.Scan(&usage); err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, rows.Err()
+}
+
+// Check проверяет, что все секреты, на которые ссылаются настройки, существуют
+func (s *Store) Check(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.values(references(raw))
+	return err
+}
+
+// ResolveService подставляет значения секретов в URL и настройки проверки.
+// Concealer убирает подставленные значения из сообщений об ошибках проверки
+func (s *Store) ResolveService(service models.Service) (models.Service, Concealer, error) {
+	values, err := s.resolve(service.URL, &service.URL)
+	if err != nil {
+		return service, Concealer{}, err
+	}
+
+	// Настройки расшифровываются в новую структуру: исходная делит
+	// с вызывающим указатели и карты
+	var cfg models.ProbeConfig
+	cfgValues, err := s.resolve(service.ProbeConfig, &cfg)
+	if err != nil {
+		return service, Concealer{}, err
+	}
+	for name, value := range cfgValues {
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = value
+	}
+	service.ProbeConfig = cfg
+	return service, newConcealer(values), nil
+}
+
+// ResolveChannel подставляет значения секретов в настройки канала.
+// Concealer убирает подставленные значения из ошибок отправки
+func (s *Store) ResolveChannel(channel models.Channel) (models.Channel, Concealer, error) {
+	var cfg models.ChannelConfig
+	values, err := s.resolve(channel.Config, &cfg)
+	if err != nil {
+		return channel, Concealer{}, err
+	}
+	channel.Config = cfg
+	return channel, newConcealer(values), nil
+}
+
+// resolve заменяет ссылки в JSON представлении v, читает результат в out
+// и возвращает подставленные значения
+func (s *Store) resolve(v, out interface{}) (map[string]string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.values(references(raw))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 0 {
+		if raw, err = substitute(raw, values); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, json.Unmarshal(raw, out)
+}
+
+// Concealer возвращает ссылки ${secret:имя} на место подставленных значений.
+// Ошибки net/http и драйверов содержат адрес целиком, вместе с параметрами
+// и паролем, а сообщения об ошибках сохраняются в истории и уходят в оповещения
+type Concealer struct {
+	replacer *strings.Replacer
+}
+
+// newConcealer заменяет значения как есть и в URL-кодировке, длинные раньше
+// коротких, чтобы значение-префикс не оставило хвост другого
+func newConcealer(values map[string]string) Concealer {
+	type pair struct{ value, ref string }
+
+	var pairs []pair
+	seen := make(map[string]bool)
+	for name, value := range values {
+		for _, v := range []string{value, url.QueryEscape(value), url.PathEscape(value)} {
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			pairs = append(pairs, pair{v, "${secret:" + name + "}"})
+		}
+	}
+	if len(pairs) == 0 {
+		return Concealer{}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return len(pairs[i].value) > len(pairs[j].value) })
+	args := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		args = append(args, p.value, p.ref)
+	}
+	return Concealer{replacer: strings.NewReplacer(args...)}
+}
+
+// Conceal заменяет значения секретов в тексте ссылками на них
+func (c Concealer) Conceal(text string) string {
+	if c.replacer == nil {
+		return text
+	}
+	return c.replacer.Replace(text)
+}
+
+// ConcealError то же для ошибки; nil остается nil
+func (c Concealer) ConcealError(err error) error {
+	if err == nil || c.replacer == nil {
+		return err
+	}
+	return errors.New(c.Conceal(err.Error()))
+}
+
+// values загружает и расшифровывает секреты
+func (s *Store) values(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if s.aead == nil {
+		return nil, ErrDisabled
+	}
+
+	rows, err := s.db.Query(`SELECT name, value FROM secrets WHERE name = ANY($1)`, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string, len(names))
+	for rows.Next() {
+		var name string
+		var sealed []byte
+		if err := rows.Scan(&name, &sealed); err != nil {
+			return nil, err
+		}
+		if values[name], err = s.open(name, sealed); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("секрет %s не найден", name)
+		}
+	}
+	return values, nil
+}
+
+// seal шифрует значение; имя секрета входит в проверяемые данные, поэтому
+// зашифрованное значение нельзя подложить под другим именем
+func (s *Store) seal(name, value string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(value), []byte(name)), nil
+}
+
+// open расшифровывает значение, записанное seal
+func (s *Store) open(name string, sealed []byte) (string, error) {
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return "", fmt.Errorf("секрет %s поврежден", name)
+	}
+	plain, err := s.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("секрет %s не расшифровывается: неверный SECRETS_KEY?", name)
+	}
+	return string(plain), nil
+}
+
+// references имена секретов, на которые есть ссылки, без повторов
+func references(raw []byte) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range reference.FindAllSubmatch(raw, -1) {
+		name := string(match[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// substitute подставляет значения в JSON. Ссылки встречаются только внутри
+// строк, поэтому значение экранируется как содержимое строки JSON
+func substitute(raw []byte, values map[string]string) ([]byte, error) {
+	var err error
+	result := reference.ReplaceAllFunc(raw, func(match []byte) []byte {
+		name := string(reference.FindSubmatch(match)[1])
+		encoded, e := json.Marshal(values[name])
+		if e != nil {
+			err = e
+			return match
+		}
+		return encoded[1 : len(encoded)-1]
+	})
+	return result, err
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"service-monitor/internal/models"
+)
+
+func TestSealOpen(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	store, err := NewStore(nil, key)
+	assert.NoError(t, err)
+
+	sealed, err := store.seal("db-password", "p@ss")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), "p@ss")
+
+	value, err := store.open("db-password", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "p@ss", value)
+
+	// Значение привязано к имени секрета
+	_, err = store.open("other", sealed)
+	assert.Error(t, err)
+
+	_, err = NewStore(nil, "short")
+	assert.Error(t, err)
+}
+
+func TestSubstitute(t *testing.T) {
+	raw := []byte(`{"headers":{"Authorization":"Bearer ${secret:api.token}"},"url":"${secret:hook}"}`)
+	assert.Equal(t, []string{"api.token", "hook"}, references(raw))
+
+	result, err := substitute(raw, map[string]string{"api.token": `a"b`, "hook": "https://example.com/x"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"headers":{"Authorization":"Bearer a\"b"},"url":"https://example.com/x"}`, string(result))
+}
+
+func TestConceal(t *testing.T) {
+	concealer := newConcealer(map[string]string{"tok": "s3cr t/1", "short": "s3cr"})
+
+	// Ошибка net/http содержит URL целиком, вместе с параметрами
+	_, err := http.Get("http://127.0.0.1:1/health?token=" + url.QueryEscape("s3cr t/1"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "s3cr")
+
+	concealed := concealer.ConcealError(err)
+	assert.NotContains(t, concealed.Error(), "s3cr")
+	assert.Contains(t, concealed.Error(), "token=${secret:tok}")
+
+	assert.Equal(t, "pass ${secret:tok} and ${secret:short}", concealer.Conceal("pass s3cr t/1 and s3cr"))
+	assert.Equal(t, "no secrets", Concealer{}.Conceal("no secrets"))
+	assert.NoError(t, concealer.ConcealError(nil))
+}
+
+func TestRedact(t *testing.T) {
+	service := models.Service{
+		URL: "postgres://monitor:secret@db:5432/app",
+		ProbeConfig: models.ProbeConfig{
+			Headers: map[string]string{"authorization": "Bearer plain", "Accept": "application/json"},
+			Auth:    &models.AuthConfig{Type: models.AuthBasic, Username: "admin", Password: "${secret:admin}"},
+			GRPC:    &models.GRPCProbeConfig{Metadata: map[string]string{"authorization": "Bearer grpc", "x-tenant": "a"}},
+		},
+	}
+
+	redacted := RedactService(service)
+	assert.Equal(t, "postgres://monitor:"+Mask+"@db:5432/app", redacted.URL)
+	assert.Equal(t, Mask, redacted.ProbeConfig.Headers["authorization"])
+	assert.Equal(t, "application/json", redacted.ProbeConfig.Headers["Accept"])
+	assert.Equal(t, "${secret:admin}", redacted.ProbeConfig.Auth.Password)
+	assert.Equal(t, "Bearer plain", service.ProbeConfig.Headers["authorization"])
+	assert.Equal(t, Mask, redacted.ProbeConfig.GRPC.Metadata["authorization"])
+	assert.Equal(t, "a", redacted.ProbeConfig.GRPC.Metadata["x-tenant"])
+	assert.Equal(t, "Bearer grpc", service.ProbeConfig.GRPC.Metadata["authorization"])
+
+	assert.Equal(t, "monitor:"+Mask+"@tcp(db:3306)/app", RedactURL("monitor:secret@tcp(db:3306)/app"))
+
+	// Строка подключения Postgres вида key=value
+	dsn := "host=db user=monitor password='p@ss word' dbname=app"
+	assert.Equal(t, "host=db user=monitor password="+Mask+" dbname=app", RedactURL(dsn))
+	assert.Equal(t, "password="+Mask+" host=db", RedactURL("password=secret host=db"))
+	assert.Equal(t, "host=db password=${secret:pg}", RedactURL("host=db password=${secret:pg}"))
+	assert.Equal(t, "host=db user=monitor", RedactURL("host=db user=monitor"))
+	redactedDSN := RedactURL(dsn)
+	RestoreURL(&redactedDSN, dsn)
+	assert.Equal(t, dsn, redactedDSN)
+
+	RestoreURL(&redacted.URL, service.URL)
+	RestoreProbe(&redacted.ProbeConfig, service.ProbeConfig)
+	assert.Equal(t, service.URL, redacted.URL)
+	assert.Equal(t, "Bearer plain", redacted.ProbeConfig.Headers["authorization"])
+	assert.Equal(t, "Bearer grpc", redacted.ProbeConfig.GRPC.Metadata["authorization"])
+}
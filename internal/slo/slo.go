@@ -0,0 +1,248 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"service-monitor/internal/alerting"
+	"service-monitor/internal/database"
+	"service-monitor/internal/logger"
+	"service-monitor/internal/models"
+)
+
+// burnWindow окно для расчета скорости расхода бюджета
+type burnWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+var burnWindows = []burnWindow{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// Пороги multiwindow-алертов по скорости расхода: быстрый расход съедает 2%
+// 30-дневного бюджета за час, медленный - 5% за 6 часов. Короткое окно
+// подтверждает, что расход продолжается, и ускоряет разрешение алерта
+const (
+	fastBurnThreshold = 14.4
+	slowBurnThreshold = 6
+)
+
+// evaluateInterval период пересчета SLO
+const evaluateInterval = time.Minute
+
+// Evaluator считает бюджет ошибок и скорость его расхода по health_checks
+// и открывает алерты при быстром и медленном расходе
+type Evaluator struct {
+	db     *database.DB
+	alerts *alerting.Manager
+	logger *logger.Logger
+}
+
+func NewEvaluator(db *database.DB, alerts *alerting.Manager, logger *logger.Logger) *Evaluator {
+	return &Evaluator{
+		db:     db,
+		alerts: alerts,
+		logger: logger,
+	}
+}
+
+// SLOColumns список колонок slos в порядке, который ожидает ScanSLO
+const SLOColumns = `id, name, service_id, group_name, objective, target, latency_threshold, window_days, created_at`
+
+// ScanSLO читает строку, выбранную через SLOColumns
+func ScanSLO(row database.Scanner, slo *models.SLO) error {
+	return row.Scan(
+		&slo.ID,
+		&slo.Name,
+		&slo.ServiceID,
+		&slo.Group,
+		&slo.Objective,
+		&slo.Target,
+		&slo.LatencyThreshold,
+		&slo.WindowDays,
+		&slo.CreatedAt,
+	)
+}
+
+// Run пересчитывает все SLO каждую минуту до отмены ctx
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(evaluateInterval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluateAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Evaluator) evaluateAll() {
+	rows, err := e.db.Query(`SELECT ` + SLOColumns + ` FROM slos`)
+	if err != nil {
+		e.logger.Error("Ошибка получения SLO:", err)
+		return
+	}
+
+	var slos []models.SLO
+	for rows.Next() {
+		var slo models.SLO
+		if err := ScanSLO(rows, &slo); err != nil {
+			e.logger.Error("Ошибка чтения SLO:", err)
+			rows.Close()
+			return
+		}
+		slos = append(slos, slo)
+	}
+	rows.Close()
+
+	for _, slo := range slos {
+		status, err := e.Status(slo)
+		if err != nil {
+			e.logger.Errorf("Ошибка расчета SLO %s: %v", slo.Name, err)
+			continue
+		}
+		e.updateAlerts(slo, status)
+	}
+}
+
+// Status считает SLI, остаток бюджета и скорость расхода по окнам
+func (e *Evaluator) Status(slo models.SLO) (*models.SLOStatus, error) {
+	// Для latency успешная проверка должна уложиться в порог, для availability порог не задан
+	threshold := 0
+	if slo.Objective == models.ObjectiveLatency {
+		threshold = slo.LatencyThreshold
+	}
+	// Успех определяется итоговым статусом сервиса по кворуму локаций, как
+	// в uptime: сбой из одной локации не расходует бюджет. Для проверок до
+	// появления service_status берем статус самой проверки
+	good := `COALESCE(hc.service_status, hc.status) <> 'unhealthy' AND ($4 = 0 OR hc.response_time <= $4)`
+
+	columns := []string{
+		`COUNT(*) FILTER (WHERE hc.checked_at >= NOW() - $3 * INTERVAL '1 day')`,
+		fmt.Sprintf(`COUNT(*) FILTER (WHERE hc.checked_at >= NOW() - $3 * INTERVAL '1 day' AND %s)`, good),
+	}
+	for _, w := range burnWindows {
+		since := fmt.Sprintf(`hc.checked_at >= NOW() - INTERVAL '%d seconds'`, int(w.Duration.Seconds()))
+		columns = append(columns,
+			fmt.Sprintf(`COUNT(*) FILTER (WHERE %s)`, since),
+			fmt.Sprintf(`COUNT(*) FILTER (WHERE %s AND %s)`, since, good),
+		)
+	}
+
+	query := `
+		SELECT ` + strings.Join(columns, ",\n\t\t") + `
+		FROM health_checks hc
+		JOIN services s ON s.id = hc.service_id
+		WHERE (($1::int IS NOT NULL AND hc.service_id = $1) OR ($1::int IS NULL AND s.group_name = $2))
+		  AND hc.checked_at >= NOW() - GREATEST($3 * INTERVAL '1 day', INTERVAL '6 hours')
+	`
+
+	counts := make([]int, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+
+	if err := e.db.QueryRow(query, slo.ServiceID, slo.Group, slo.WindowDays, threshold).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	status := &models.SLOStatus{
+		TotalChecks:     counts[0],
+		GoodChecks:      counts[1],
+		SLI:             sli(counts[1], counts[0]),
+		BudgetRemaining: budgetRemaining(counts[1], counts[0], slo.Target),
+		BurnRates:       make(map[string]float64, len(burnWindows)),
+	}
+	for i, w := range burnWindows {
+		status.BurnRates[w.Name] = burnRate(counts[3+2*i], counts[2+2*i], slo.Target)
+	}
+
+	return status, nil
+}
+
+func (e *Evaluator) updateAlerts(slo models.SLO, status *models.SLOStatus) {
+	fast := status.BurnRates["1h"] >= fastBurnThreshold && status.BurnRates["5m"] >= fastBurnThreshold
+	slow := status.BurnRates["6h"] >= slowBurnThreshold && status.BurnRates["30m"] >= slowBurnThreshold
+
+	e.setAlert(slo, models.AlertKindFastBurn, models.SeverityCritical, fast,
+		fmt.Sprintf("SLO %s: быстрый расход бюджета ошибок (x%.1f за 1ч), осталось %.1f%%",
+			slo.Name, status.BurnRates["1h"], status.BudgetRemaining*100))
+	e.setAlert(slo, models.AlertKindSlowBurn, models.SeverityWarning, slow,
+		fmt.Sprintf("SLO %s: медленный расход бюджета ошибок (x%.1f за 6ч), осталось %.1f%%",
+			slo.Name, status.BurnRates["6h"], status.BudgetRemaining*100))
+}
+
+func (e *Evaluator) setAlert(slo models.SLO, kind, severity string, firing bool, message string) {
+	fingerprint := SLOFingerprint(slo.ID, kind)
+
+	if !firing {
+		if _, err := e.alerts.Resolve(fingerprint); err != nil {
+			e.logger.Error("Ошибка разрешения алерта SLO:", err)
+		}
+		return
+	}
+
+	alert := models.Alert{
+		SLOID:       &slo.ID,
+		Message:     message,
+		Severity:    severity,
+		Kind:        kind,
+		Fingerprint: fingerprint,
+	}
+	if slo.ServiceID != nil {
+		alert.ServiceID = *slo.ServiceID
+	}
+
+	if _, err := e.alerts.Fire(alert); err != nil {
+		e.logger.Error("Ошибка создания алерта SLO:", err)
+	}
+}
+
+// SLOFingerprint отпечаток алерта о расходе бюджета SLO
+func SLOFingerprint(sloID int, kind string) string {
+	return fmt.Sprintf("slo:%d:%s", sloID, kind)
+}
+
+// sli фактический процент успешных проверок; без данных считается 100%
+func sli(good, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(good) * 100 / float64(total)
+}
+
+// budgetRemaining доля оставшегося бюджета ошибок: 1 - бюджет не тронут,
+// 0 - израсходован полностью, меньше 0 - SLO нарушен
+func budgetRemaining(good, total int, target float64) float64 {
+	if total == 0 {
+		return 1
+	}
+	return 1 - badRatio(good, total)/allowedRatio(target)
+}
+
+// burnRate во сколько раз доля ошибок превышает допустимую целью
+func burnRate(good, total int, target float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return badRatio(good, total) / allowedRatio(target)
+}
+
+func badRatio(good, total int) float64 {
+	return float64(total-good) / float64(total)
+}
+
+func allowedRatio(target float64) float64 {
+	return 1 - target/100
+}
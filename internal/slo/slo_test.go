@@ -0,0 +1,32 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLI(t *testing.T) {
+	assert.Equal(t, 100.0, sli(0, 0))
+	assert.InDelta(t, 99.5, sli(995, 1000), 1e-9)
+}
+
+func TestBudgetRemaining(t *testing.T) {
+	// Нет данных - бюджет не тронут
+	assert.Equal(t, 1.0, budgetRemaining(0, 0, 99.9))
+
+	// 99.9%: на 10000 проверок допустимо 10 ошибок
+	assert.InDelta(t, 1.0, budgetRemaining(10000, 10000, 99.9), 1e-9)
+	assert.InDelta(t, 0.5, budgetRemaining(9995, 10000, 99.9), 1e-9)
+	assert.InDelta(t, 0.0, budgetRemaining(9990, 10000, 99.9), 1e-9)
+	assert.InDelta(t, -1.0, budgetRemaining(9980, 10000, 99.9), 1e-9)
+}
+
+func TestBurnRate(t *testing.T) {
+	assert.Equal(t, 0.0, burnRate(0, 0, 99.9))
+	assert.InDelta(t, 1.0, burnRate(999, 1000, 99.9), 1e-9)
+	assert.InDelta(t, 14.4, burnRate(9856, 10000, 99.9), 1e-9)
+
+	// 95% проверок быстрее порога: 10% медленных - расход x2
+	assert.InDelta(t, 2.0, burnRate(90, 100, 95), 1e-9)
+}
@@ -0,0 +1,206 @@
+package uptime
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/database"
+	"service-monitor/internal/models"
+)
+
+// gapChecks сколько пропущенных проверок подряд превращают время в неизвестное
+const gapChecks = 3
+
+// minCheckInterval планировщик не проверяет сервисы чаще
+const minCheckInterval = 30 * time.Second
+
+// Calculator загружает историю проверок и окна обслуживания из БД
+type Calculator struct {
+	db *database.DB
+}
+
+func NewCalculator(db *database.DB) *Calculator {
+	return &Calculator{db: db}
+}
+
+// ForService считает uptime сервиса за [from, to). Сервис доступен, пока его
+// итоговый статус не unhealthy - деградация на uptime не влияет
+func (c *Calculator) ForService(service models.Service, from, to time.Time) (Result, error) {
+	maxGap := MaxGap(service)
+
+	samples, err := c.samples(service.ID, from.Add(-maxGap), to)
+	if err != nil {
+		return Result{}, err
+	}
+
+	maintenance, err := c.Maintenance(service.ID, from, to)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Calculate(from, to, samples, maintenance, maxGap), nil
+}
+
+// ForServices считает uptime нескольких сервисов за [from, to) двумя
+// запросами: история всех сервисов и все окна обслуживания разом
+func (c *Calculator) ForServices(services []models.Service, from, to time.Time) (map[int]Result, error) {
+	results := make(map[int]Result, len(services))
+	if len(services) == 0 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(services))
+	var maxGap time.Duration
+	for i, service := range services {
+		ids[i] = int64(service.ID)
+		if gap := MaxGap(service); gap > maxGap {
+			maxGap = gap
+		}
+	}
+
+	samples, err := c.samplesByService(ids, from.Add(-maxGap), to)
+	if err != nil {
+		return nil, err
+	}
+	maintenance, err := c.maintenanceByService(ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		gap := MaxGap(service)
+
+		// История загружена с запасом на наибольший интервал; лишние
+		// проверки до from - gap отбрасываем, как в ForService
+		history := samples[service.ID]
+		start := 0
+		for start < len(history) && history[start].At.Before(from.Add(-gap)) {
+			start++
+		}
+
+		windows := append(append([]Interval(nil), maintenance[0]...), maintenance[service.ID]...)
+		results[service.ID] = Calculate(from, to, history[start:], windows, gap)
+	}
+	return results, nil
+}
+
+// MaxGap как долго статус последней проверки считается действующим
+func MaxGap(service models.Service) time.Duration {
+	interval := time.Duration(service.CheckInterval) * time.Second
+	if interval < minCheckInterval {
+		interval = minCheckInterval
+	}
+	return gapChecks * interval
+}
+
+func (c *Calculator) samples(serviceID int, from, to time.Time) ([]Sample, error) {
+	// Для проверок до появления service_status берем статус самой проверки
+	query := `
+		SELECT checked_at, COALESCE(service_status, status) <> $4
+		FROM health_checks
+		WHERE service_id = $1 AND checked_at >= $2 AND checked_at < $3
+		ORDER BY checked_at
+	`
+
+	rows, err := c.db.Query(query, serviceID, from, to, models.StatusUnhealthy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []Sample
+	for rows.Next() {
+		var sample Sample
+		if err := rows.Scan(&sample.At, &sample.Up); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// samplesByService история проверок нескольких сервисов по возрастанию времени
+func (c *Calculator) samplesByService(ids []int64, from, to time.Time) (map[int][]Sample, error) {
+	query := `
+		SELECT service_id, checked_at, COALESCE(service_status, status) <> $4
+		FROM health_checks
+		WHERE service_id = ANY($1) AND checked_at >= $2 AND checked_at < $3
+		ORDER BY service_id, checked_at
+	`
+
+	rows, err := c.db.Query(query, pq.Array(ids), from, to, models.StatusUnhealthy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make(map[int][]Sample)
+	for rows.Next() {
+		var serviceID int
+		var sample Sample
+		if err := rows.Scan(&serviceID, &sample.At, &sample.Up); err != nil {
+			return nil, err
+		}
+		samples[serviceID] = append(samples[serviceID], sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// maintenanceByService окна обслуживания нескольких сервисов, пересекающиеся
+// с [from, to); общие окна без сервиса лежат под ключом 0
+func (c *Calculator) maintenanceByService(ids []int64, from, to time.Time) (map[int][]Interval, error) {
+	query := `
+		SELECT COALESCE(service_id, 0), starts_at, ends_at
+		FROM maintenance_windows
+		WHERE (service_id = ANY($1) OR service_id IS NULL)
+		  AND starts_at < $3 AND ends_at > $2
+	`
+
+	rows, err := c.db.Query(query, pq.Array(ids), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make(map[int][]Interval)
+	for rows.Next() {
+		var serviceID int
+		var w Interval
+		if err := rows.Scan(&serviceID, &w.Start, &w.End); err != nil {
+			return nil, err
+		}
+		windows[serviceID] = append(windows[serviceID], w)
+	}
+
+	return windows, rows.Err()
+}
+
+// Maintenance окна обслуживания сервиса, пересекающиеся с [from, to)
+func (c *Calculator) Maintenance(serviceID int, from, to time.Time) ([]Interval, error) {
+	query := `
+		SELECT starts_at, ends_at
+		FROM maintenance_windows
+		WHERE (service_id = $1 OR service_id IS NULL)
+		  AND starts_at < $3 AND ends_at > $2
+	`
+
+	rows, err := c.db.Query(query, serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []Interval
+	for rows.Next() {
+		var w Interval
+		if err := rows.Scan(&w.Start, &w.End); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, rows.Err()
+}
@@ -0,0 +1,144 @@
+package uptime
+
+import (
+	"sort"
+	"time"
+)
+
+// Sample статус сервиса, зафиксированный проверкой
+type Sample struct {
+	At time.Time
+	Up bool
+}
+
+// Interval полуинтервал времени [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Result время в каждом состоянии за окно
+type Result struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	Uptime             float64   `json:"uptime"` // процент от времени с известным статусом вне обслуживания
+	UpSeconds          float64   `json:"up_seconds"`
+	DownSeconds        float64   `json:"down_seconds"`
+	MaintenanceSeconds float64   `json:"maintenance_seconds"`
+	UnknownSeconds     float64   `json:"unknown_seconds"`
+}
+
+// Calculate считает uptime по времени, а не по количеству проверок: статус
+// действует от проверки до следующей, но не дольше maxGap (0 - без ограничения),
+// после чего статус считается неизвестным. Время окон обслуживания исключается
+// и из числителя, и из знаменателя. samples могут начинаться раньше from -
+// последняя такая проверка задает статус на начало окна
+func Calculate(from, to time.Time, samples []Sample, maintenance []Interval, maxGap time.Duration) Result {
+	result := Result{From: from, To: to}
+	if !to.After(from) {
+		return result
+	}
+
+	sorted := append([]Sample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+
+	windows := merge(clip(maintenance, from, to))
+
+	var up, down time.Duration
+	for i, sample := range sorted {
+		end := to
+		if i+1 < len(sorted) && sorted[i+1].At.Before(end) {
+			end = sorted[i+1].At
+		}
+		if maxGap > 0 && sample.At.Add(maxGap).Before(end) {
+			end = sample.At.Add(maxGap)
+		}
+
+		start := sample.At
+		if start.Before(from) {
+			start = from
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		d := end.Sub(start) - overlap(Interval{start, end}, windows)
+		if sample.Up {
+			up += d
+		} else {
+			down += d
+		}
+	}
+
+	var excluded time.Duration
+	for _, w := range windows {
+		excluded += w.End.Sub(w.Start)
+	}
+
+	result.UpSeconds = up.Seconds()
+	result.DownSeconds = down.Seconds()
+	result.MaintenanceSeconds = excluded.Seconds()
+	result.UnknownSeconds = (to.Sub(from) - up - down - excluded).Seconds()
+	if up+down > 0 {
+		result.Uptime = float64(up) * 100 / float64(up+down)
+	}
+
+	return result
+}
+
+// clip обрезает интервалы по окну [from, to) и отбрасывает пустые
+func clip(intervals []Interval, from, to time.Time) []Interval {
+	var out []Interval
+	for _, iv := range intervals {
+		if iv.Start.Before(from) {
+			iv.Start = from
+		}
+		if iv.End.After(to) {
+			iv.End = to
+		}
+		if iv.End.After(iv.Start) {
+			out = append(out, iv)
+		}
+	}
+	return out
+}
+
+// merge объединяет пересекающиеся интервалы
+func merge(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+
+	out := []Interval{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &out[len(out)-1]
+		if !iv.Start.After(last.End) {
+			if iv.End.After(last.End) {
+				last.End = iv.End
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// overlap длительность пересечения интервала с непересекающимися интервалами
+func overlap(iv Interval, windows []Interval) time.Duration {
+	var total time.Duration
+	for _, w := range windows {
+		start, end := w.Start, w.End
+		if iv.Start.After(start) {
+			start = iv.Start
+		}
+		if iv.End.Before(end) {
+			end = iv.End
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
@@ -0,0 +1,113 @@
+package uptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	r := Calculate(at(0), at(60), nil, nil, 0)
+	assert.Equal(t, 0.0, r.Uptime)
+	assert.Equal(t, 3600.0, r.UnknownSeconds)
+
+	r = Calculate(at(60), at(0), []Sample{{at(10), true}}, nil, 0)
+	assert.Equal(t, 0.0, r.UpSeconds)
+}
+
+func TestCalculateWeightedByTime(t *testing.T) {
+	// Одна проверка со сбоем и много успешных подряд не должны давать
+	// одинаковый вес: считается время, а не количество проверок
+	samples := []Sample{
+		{at(0), false},
+		{at(30), true},
+		{at(40), true},
+		{at(50), true},
+	}
+
+	r := Calculate(at(0), at(60), samples, nil, 0)
+	assert.InDelta(t, 50.0, r.Uptime, 1e-9)
+	assert.Equal(t, 1800.0, r.UpSeconds)
+	assert.Equal(t, 1800.0, r.DownSeconds)
+	assert.Equal(t, 0.0, r.UnknownSeconds)
+}
+
+func TestCalculateIntervalChange(t *testing.T) {
+	// Интервал проверки сменился с 1 минуты на 10: по количеству проверок
+	// вышло бы 10/11, по времени - 50%
+	var samples []Sample
+	for m := 0; m < 10; m++ {
+		samples = append(samples, Sample{at(m), true})
+	}
+	samples = append(samples, Sample{at(10), false})
+
+	r := Calculate(at(0), at(20), samples, nil, 0)
+	assert.InDelta(t, 50.0, r.Uptime, 1e-9)
+}
+
+func TestCalculateSampleBeforeWindow(t *testing.T) {
+	// Статус на начало окна берется из последней проверки до него
+	samples := []Sample{
+		{at(-5), false},
+		{at(30), true},
+	}
+
+	r := Calculate(at(0), at(60), samples, nil, 0)
+	assert.InDelta(t, 50.0, r.Uptime, 1e-9)
+	assert.Equal(t, 1800.0, r.DownSeconds)
+}
+
+func TestCalculateMaxGap(t *testing.T) {
+	// Мониторинг не работал с 10 по 50 минуту: это время неизвестно, а не up
+	samples := []Sample{
+		{at(0), true},
+		{at(5), true},
+		{at(50), false},
+	}
+
+	r := Calculate(at(0), at(60), samples, nil, 5*time.Minute)
+	assert.Equal(t, 600.0, r.UpSeconds)
+	assert.Equal(t, 300.0, r.DownSeconds)
+	assert.Equal(t, 2700.0, r.UnknownSeconds)
+	assert.InDelta(t, 66.667, r.Uptime, 0.001)
+}
+
+func TestCalculateMaintenance(t *testing.T) {
+	samples := []Sample{
+		{at(0), true},
+		{at(20), false},
+		{at(40), true},
+	}
+
+	// Сбой с 20 по 40 минуту целиком пришелся на обслуживание,
+	// окна пересекаются и выходят за границы расчета
+	maintenance := []Interval{
+		{at(15), at(30)},
+		{at(25), at(40)},
+		{at(-30), at(-10)},
+	}
+
+	r := Calculate(at(0), at(60), samples, maintenance, 0)
+	assert.Equal(t, 100.0, r.Uptime)
+	assert.Equal(t, 1500.0, r.MaintenanceSeconds)
+	assert.Equal(t, 2100.0, r.UpSeconds)
+	assert.Equal(t, 0.0, r.DownSeconds)
+	assert.Equal(t, 0.0, r.UnknownSeconds)
+}
+
+func TestCalculateUnsortedSamples(t *testing.T) {
+	samples := []Sample{
+		{at(30), true},
+		{at(0), false},
+	}
+
+	r := Calculate(at(0), at(60), samples, nil, 0)
+	assert.InDelta(t, 50.0, r.Uptime, 1e-9)
+}
@@ -1,11 +1,29 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"service-monitor/internal/agent"
+	"service-monitor/internal/alerting"
 	"service-monitor/internal/api"
+	"service-monitor/internal/cluster"
 	"service-monitor/internal/config"
 	"service-monitor/internal/database"
+	"service-monitor/internal/models"
 	"service-monitor/internal/monitor"
 	"service-monitor/internal/logger"
+	"service-monitor/internal/notify"
+	"service-monitor/internal/oncall"
+	"service-monitor/internal/probe"
+	"service-monitor/internal/routing"
+	"service-monitor/internal/rules"
+	"service-monitor/internal/secrets"
+	"service-monitor/internal/slo"
 )
 
 func main() {
@@ -18,6 +36,24 @@ func main() {
 		logger.Fatal("Ошибка загрузки конфигурации:", err)
 	}
 
+	// Отмена контекста по SIGINT/SIGTERM запускает остановку
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Сетевые настройки проверок по умолчанию, в том числе для агента
+	network := models.NetworkConfig{Proxy: cfg.ProbeProxy, Resolver: cfg.ProbeResolver, IPVersion: cfg.ProbeIPVersion}
+	if err := probe.ValidateDefaultNetwork(network); err != nil {
+		logger.Fatal("Неверные сетевые настройки проверок:", err)
+	}
+	probe.SetDefaultNetwork(network)
+	probe.SetHostConcurrency(cfg.CheckHostConcurrency)
+
+	// В режиме агента нет БД и API: только проверки по заданиям центрального сервера
+	if cfg.Mode == "agent" {
+		agent.New(cfg, logger).Run(ctx)
+		return
+	}
+
 	// Подключение к базе данных
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -30,19 +66,111 @@ func main() {
 		logger.Fatal("Ошибка миграции базы данных:", err)
 	}
 
+	// Хранилище секретов для учетных данных проверок и каналов
+	secretStore, err := secrets.NewStore(db, cfg.SecretsKey)
+	if err != nil {
+		logger.Fatal("Ошибка инициализации хранилища секретов:", err)
+	}
+
 	// Создание мониторинга
-	monitorService := monitor.NewService(db, logger)
+	alertManager := alerting.NewManager(db, logger)
+	monitorService := monitor.NewService(cfg, db, alertManager, secretStore, logger)
+	sloEvaluator := slo.NewEvaluator(db, alertManager, logger)
+	notifier := notify.NewNotifier(cfg, db, secretStore, logger)
+	escalator := oncall.NewEscalator(db, oncall.NewResolver(db), notifier, logger)
+	ruleEvaluator := rules.NewEvaluator(db, alertManager, notifier, logger)
+	dispatcher := routing.NewDispatcher(db, notifier, logger)
+
+	// Выбор лидера: при нескольких экземплярах проверки выполняет только один
+	elector := cluster.NewElector(db, logger, cfg.InstanceID, time.Duration(cfg.LeaderCheckInterval)*time.Second)
 
 	// Создание API сервера
-	server := api.NewServer(cfg, db, monitorService, logger)
+	server := api.NewServer(cfg, db, monitorService, elector, sloEvaluator, ruleEvaluator, secretStore, logger)
 
-	// Запуск мониторинга в фоне
-	go monitorService.Start()
+	// Запуск мониторинга и фоновых задач, пока экземпляр является лидером
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		elector.Run(ctx, leaderTasks(monitorService.Run, sloEvaluator.Run, ruleEvaluator.Run, escalator.Run,
+			dispatcher.Run, notifier.Run))
+	}()
+
+	// Запись результатов проверок пачками; останавливается последней,
+	// после мониторинга и API, чтобы записать все результаты
+	writerCtx, stopWriter := context.WithCancel(context.Background())
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		monitorService.RunWriter(writerCtx)
+	}()
 
 	logger.Info("Сервер мониторинга запущен на порту:", cfg.Port)
 	
 	// Запуск сервера
-	if err := server.Run(); err != nil {
-		logger.Fatal("Ошибка запуска сервера:", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run()
+	}()
+
+	// Сервер, не сумевший запуститься, после корректной остановки
+	// завершается с ненулевым кодом, чтобы оркестратор увидел сбой
+	failed := false
+	select {
+	case <-ctx.Done():
+		logger.Info("Получен сигнал остановки")
+	case err := <-serverErr:
+		if err != nil {
+			logger.Error("Ошибка запуска сервера:", err)
+			failed = true
+		}
+		stop()
+	}
+
+	shutdown(cfg, server, monitorService, monitorDone, logger)
+
+	// Записываем оставшиеся результаты до закрытия пула соединений с БД
+	stopWriter()
+	<-writerDone
+	logger.Info("Сервер мониторинга остановлен")
+
+	if failed {
+		// os.Exit не выполняет отложенные вызовы
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+// leaderTasks объединяет фоновые задачи лидера в одну: все запускаются
+// параллельно и завершаются вместе с контекстом лидерства
+func leaderTasks(tasks ...func(ctx context.Context)) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, task := range tasks {
+			wg.Add(1)
+			go func(task func(ctx context.Context)) {
+				defer wg.Done()
+				task(ctx)
+			}(task)
+		}
+		wg.Wait()
+	}
+}
+
+// shutdown останавливает HTTP сервер и мониторинг в пределах SHUTDOWN_TIMEOUT.
+// Пул соединений с БД закрывается отложенным вызовом в main
+func shutdown(cfg *config.Config, server *api.Server, monitorService *monitor.Service, monitorDone <-chan struct{}, logger *logger.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Ошибка остановки HTTP сервера:", err)
+	}
+
+	select {
+	case <-monitorDone:
+	case <-ctx.Done():
+		logger.Error("Проверки не завершились за отведенное время, прерываем")
+		monitorService.Abort()
+		<-monitorDone
 	}
 }
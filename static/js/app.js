@@ -125,7 +125,8 @@ class ServiceMonitor {
         await Promise.all([
             this.loadServices(),
             this.loadAlerts(),
-            this.loadStats()
+            this.loadStats(),
+            this.loadSLOs()
         ]);
     }
 
@@ -166,6 +167,18 @@ class ServiceMonitor {
         }
     }
 
+    async loadSLOs() {
+        try {
+            const response = await fetch('/api/v1/slos');
+            if (!response.ok) throw new Error('Ошибка загрузки SLO');
+            
+            const slos = await response.json();
+            this.renderSLOs(slos);
+        } catch (error) {
+            console.error('Ошибка загрузки SLO:', error);
+        }
+    }
+
     renderServices(services) {
         const grid = document.getElementById('servicesGrid');
         grid.innerHTML = '';
@@ -192,8 +205,8 @@ class ServiceMonitor {
         const card = document.createElement('div');
         card.className = `service-card service-card--${service.last_status || 'unknown'}`;
         
-        const statusClass = service.last_status === 'healthy' ? 'healthy' : 
-                           service.last_status === 'unhealthy' ? 'unhealthy' : 'unknown';
+        const statusClass = ['healthy', 'unhealthy', 'degraded'].includes(service.last_status) ?
+                           service.last_status : 'unknown';
         
         const lastCheck = service.last_check ? 
             new Date(service.last_check).toLocaleString('ru-RU') : 'Не проверялся';
@@ -276,10 +289,46 @@ class ServiceMonitor {
         return item;
     }
 
+    renderSLOs(slos) {
+        const list = document.getElementById('sloList');
+        list.innerHTML = '';
+
+        if (slos.length === 0) {
+            list.innerHTML = '<p class="empty-state">SLO не заданы</p>';
+            return;
+        }
+
+        slos.forEach(slo => {
+            const status = slo.status;
+            const budget = Math.max(0, Math.min(1, status.budget_remaining));
+            const modifier = status.budget_remaining <= 0 ? 'breached' :
+                             status.budget_remaining < 0.25 ? 'warning' : 'ok';
+            const target = slo.objective === 'latency' ?
+                `${slo.target}% проверок быстрее ${slo.latency_threshold} мс` : `${slo.target}% доступности`;
+
+            const item = document.createElement('div');
+            item.className = `slo-item slo-item--${modifier}`;
+            item.innerHTML = `
+                <div class="slo-item__name">${this.escapeHtml(slo.name)}</div>
+                <div class="slo-item__detail">${target} за ${slo.window_days} дн.</div>
+                <div class="slo-item__detail">Фактически: ${status.sli.toFixed(3)}%</div>
+                <div class="slo-item__budget">
+                    <div class="slo-item__budget-fill" style="width: ${(budget * 100).toFixed(1)}%"></div>
+                </div>
+                <div class="slo-item__detail">
+                    Бюджет ошибок: ${(status.budget_remaining * 100).toFixed(1)}%,
+                    расход 1ч: x${status.burn_rates['1h'].toFixed(1)}, 6ч: x${status.burn_rates['6h'].toFixed(1)}
+                </div>
+            `;
+            list.appendChild(item);
+        });
+    }
+
     renderStats(stats) {
         document.getElementById('totalServices').textContent = stats.total_services;
         document.getElementById('healthyServices').textContent = stats.healthy_services;
         document.getElementById('unhealthyServices').textContent = stats.unhealthy_services;
+        document.getElementById('degradedServices').textContent = stats.degraded_services;
         document.getElementById('averageUptime').textContent = `${stats.average_uptime.toFixed(1)}%`;
         document.getElementById('activeAlerts').textContent = stats.active_alerts;
     }
@@ -292,7 +341,9 @@ class ServiceMonitor {
             name: formData.get('name'),
             url: formData.get('url'),
             check_interval: parseInt(formData.get('check_interval')) || 30,
-            timeout: parseInt(formData.get('timeout')) || 10
+            timeout: parseInt(formData.get('timeout')) || 10,
+            degraded_threshold: parseInt(formData.get('degraded_threshold')) || 0,
+            degraded_p95_threshold: parseInt(formData.get('degraded_p95_threshold')) || 0
         };
 
         try {
@@ -405,7 +456,7 @@ class ServiceMonitor {
                                     ${this.getStatusIcon(check.status)} ${this.getStatusText(check.status)}
                                 </span>
                             </td>
-                            <td>${check.response_time}ms</td>
+                            <td title="${this.formatTimings(check.timings)}">${check.response_time}ms</td>
                             <td>${check.error_message || '-'}</td>
                         </tr>
                     `).join('')}
@@ -518,6 +569,7 @@ class ServiceMonitor {
         switch (status) {
             case 'healthy': return '✅';
             case 'unhealthy': return '❌';
+            case 'degraded': return '🐢';
             default: return '❓';
         }
     }
@@ -526,10 +578,17 @@ class ServiceMonitor {
         switch (status) {
             case 'healthy': return 'Работает';
             case 'unhealthy': return 'Не работает';
+            case 'degraded': return 'Медленно';
             default: return 'Неизвестно';
         }
     }
 
+    formatTimings(timings) {
+        if (!timings) return '';
+        return `DNS ${timings.dns}ms, TCP ${timings.connect}ms, TLS ${timings.tls}ms, ` +
+               `TTFB ${timings.ttfb}ms, загрузка ${timings.transfer}ms`;
+    }
+
     escapeHtml(text) {
         const div = document.createElement('div');
         div.textContent = text;